@@ -1,24 +1,141 @@
 package cmd
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/backup"
 	"github.com/spf13/cobra"
+	"os"
+	"time"
 )
 
+// backupOptions represents the options of the backup command
+type backupOptions struct {
+	BufferSize  int
+	CopyWorkers int
+	DryRun      bool
+	Exclude     []string
+	Name        string
+	OmitHidden  bool
+	Paranoid    bool
+	Stdin       bool
+	StdinName   string
+	Threads     int
+	Watch       bool
+	WatchEvery  time.Duration
+}
+
+var backupOpts backupOptions
+
 // backupCmd represents the backup command
 var backupCmd = &cobra.Command{
-	Use:   "backup",
+	Use:   "backup <paths>",
 	Short: "Create a new backup from the paths provided",
 	Long: `backup will create a new backup with the current date and the name provided in
-the repository.`,
-	Run: parseCmd,
+the repository.
+If a profile of the config file is provided, its repository, paths, name and
+exclusions will be used unless they are set in the command line.
+The files are hashed by --threads workers and copied to the repository by
+--copy-workers workers at the same time. The files whose size doesn't match any
+object of the repository are hashed while they are copied, reading them once.
+With --dry-run, the files are listed and hashed, and it reports how many of them
+would be new in the repository and which ones would be skipped, without
+modifying it.
+With --stdin, the standard input is stored as a single file named after
+--stdin-filename (e.g. "pg_dump db | gkup backup --stdin --stdin-filename db.sql").
+The commands of the [hooks] section of the config file, or of the profile, are
+run before listing the files (pre-backup), after writing the snapshot
+(post-backup) and when the backup fails (on-failure). They receive the
+variables GKUP_HOOK, GKUP_REPO, GKUP_SNAPSHOT_NAME and, after the backup,
+GKUP_STATUS, GKUP_SNAPSHOT, GKUP_FILES, GKUP_BYTES, GKUP_NEW_FILES and
+GKUP_NEW_BYTES, or GKUP_ERROR if it failed. They are killed after "timeout"
+(e.g. "5m"), and "on-error" defines whether a failed hook aborts the backup
+("abort", default) or is only logged ("continue"). Hooks are not run in dry runs.
+With --watch, it keeps watching the paths for changes after the backup and
+creates a new snapshot with the files changed at most every --watch-interval,
+without listing and hashing the rest again, until it's stopped. The errors of
+single files are always omitted in this mode.`,
+	RunE: runBackup,
 }
 
 func init() {
 	rootCmd.AddCommand(backupCmd)
 
-	addFlagBackupName(backupCmd)
-	addFlagBufferSize(backupCmd)
-	addFlagNumberOfThreads(backupCmd)
-	addFlagOmitHidden(backupCmd)
-	addFlagReadSymLinks(backupCmd)
+	addFlagBackupName(backupCmd, &backupOpts.Name)
+	addFlagBufferSize(backupCmd, &backupOpts.BufferSize)
+	addFlagCopyWorkers(backupCmd, &backupOpts.CopyWorkers)
+	addFlagDryRun(backupCmd, &backupOpts.DryRun)
+	addFlagExclude(backupCmd, &backupOpts.Exclude)
+	addFlagNumberOfThreads(backupCmd, &backupOpts.Threads)
+	addFlagOmitHidden(backupCmd, &backupOpts.OmitHidden)
+	addFlagParanoid(backupCmd, &backupOpts.Paranoid)
+	addFlagProfile(backupCmd, &profile)
+	addFlagStdin(backupCmd, &backupOpts.Stdin)
+	addFlagStdinFileName(backupCmd, &backupOpts.StdinName)
+	addFlagWatch(backupCmd, &backupOpts.Watch)
+	addFlagWatchInterval(backupCmd, &backupOpts.WatchEvery)
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	configProfileString(cmd, "name", &backupOpts.Name)
+	configInt(cmd, "buffer-size", &backupOpts.BufferSize)
+	configInt(cmd, "threads", &backupOpts.Threads)
+	configInt(cmd, "copy-workers", &backupOpts.CopyWorkers)
+	if err := configExclude(&backupOpts.Exclude); err != nil {
+		return &UsageError{Err: err}
+	}
+	if err := checkThreads(backupOpts.Threads); err != nil {
+		return err
+	}
+	var section string
+	if profile != "" {
+		section = profileKey("")
+	}
+	h, err := configHooks(section)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+	if backupOpts.Stdin {
+		if len(args) != 0 {
+			return usageErrorf("paths cannot be provided when backing up the standard input")
+		}
+		if backupOpts.DryRun {
+			return usageErrorf("--dry-run cannot be used with --stdin")
+		}
+		if backupOpts.Watch {
+			return usageErrorf("--watch cannot be used with --stdin")
+		}
+	} else {
+		if len(args) == 0 {
+			args = configProfilePaths()
+		}
+		if len(args) == 0 {
+			return usageErrorf("no files to backup, skipping empty backup")
+		}
+		if backupOpts.Watch && backupOpts.DryRun {
+			return usageErrorf("--dry-run cannot be used with --watch")
+		}
+		if backupOpts.Watch && backupOpts.WatchEvery < backup.MinWatchInterval {
+			return usageErrorf("--watch-interval must be at least %s", backup.MinWatchInterval)
+		}
+	}
+
+	opts := pkg.NewOptions()
+	opts.BufferSize = backupOpts.BufferSize
+	opts.CopyWorkers = backupOpts.CopyWorkers
+	opts.Exclude = backupOpts.Exclude
+	opts.NumberOfThreads = backupOpts.Threads
+	opts.OmitHidden = backupOpts.OmitHidden
+	opts.Paranoid = backupOpts.Paranoid
+	opts.Hooks = h
+
+	if backupOpts.Stdin {
+		return backup.Stdin(ctx, Global.RepoPath, backupOpts.Name, backupOpts.StdinName, os.Stdin, opts, Global.JSON, os.Stdout)
+	}
+	if backupOpts.Watch {
+		return backup.Watch(ctx, Global.RepoPath, backupOpts.Name, args, backupOpts.WatchEvery, opts, Global.JSON, os.Stdout)
+	}
+	if backupOpts.DryRun {
+		return backup.DryRun(ctx, Global.RepoPath, backupOpts.Name, args, opts, Global.JSON, os.Stdout)
+	}
+	return backup.Backup(ctx, Global.RepoPath, backupOpts.Name, args, opts, Global.JSON, os.Stdout)
 }
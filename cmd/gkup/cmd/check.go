@@ -1,21 +1,45 @@
 package cmd
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
 	"github.com/spf13/cobra"
+	"os"
 )
 
+// checkOptions represents the options of the check command
+type checkOptions struct {
+	BufferSize int
+	Threads    int
+}
+
+var checkOpts checkOptions
+
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check the integrity of your repository",
 	Long: `Check the integrity of the files in your repository. It will detect if the files
 are corrupted or have defects from bad copying or bad hardware.`,
-	Run: parseCmd,
+	RunE: runCheck,
 }
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
 
-	addFlagBufferSize(checkCmd)
-	addFlagNumberOfThreads(checkCmd)
+	addFlagBufferSize(checkCmd, &checkOpts.BufferSize)
+	addFlagNumberOfThreads(checkCmd, &checkOpts.Threads)
+}
+
+func runCheck(cmd *cobra.Command, _ []string) error {
+	configInt(cmd, "buffer-size", &checkOpts.BufferSize)
+	configInt(cmd, "threads", &checkOpts.Threads)
+	if err := checkThreads(checkOpts.Threads); err != nil {
+		return err
+	}
+
+	opts := pkg.NewOptions()
+	opts.BufferSize = checkOpts.BufferSize
+	opts.NumberOfThreads = checkOpts.Threads
+	return check.Check(ctx, Global.RepoPath, opts, Global.JSON, os.Stderr, os.Stdout)
 }
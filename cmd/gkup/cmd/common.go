@@ -1,109 +1,272 @@
 package cmd
 
 import (
-	"errors"
+	"bytes"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
 	"github.com/spf13/cobra"
 	"runtime"
+	"time"
 )
 
-var (
-	Args			[]string
-	BackupName      string
-	BackupDate      string
-	BufferSize      int
-	Cmd             string
-	NumberOfThreads int
-	OmitHidden      bool
-	OmitErrors      bool
-	ReadSymLinks    bool
-	RepoPath        string
-	Sum             string
-	VerboseLevel    int
-
-	ArgsErrors []error
-)
+// UsageError is the error returned when the command line provided is not valid.
+type UsageError struct {
+	Err error
+}
 
-func parseCmd(cmd *cobra.Command, args []string) {
-	Cmd = cmd.Name()
-	Args = args
+func (e *UsageError) Error() string {
+	return e.Err.Error()
 }
 
-func addFlagBackupName(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&BackupName, "name", "n", "", "backup name")
+func (e *UsageError) Unwrap() error {
+	return e.Err
 }
 
-func addFlagBackupDate(cmd *cobra.Command) {
-	d := *cmd.Flags().StringP("date", "d", "", `date of the backup to restore.
-	It format must be YYYY-MM-DD_hh-mm-ss
-	and must match with the backup date.`)
-	if len(d) != 19 {
-		ArgsErrors = append(ArgsErrors, errors.New("invalid backup date"))
-	}
-	BackupDate = d
+// usageErrorf returns a UsageError with the message formatted according to the format provided.
+func usageErrorf(format string, a ...interface{}) error {
+	return &UsageError{Err: fmt.Errorf(format, a...)}
 }
 
-func addFlagBufferSize(cmd *cobra.Command) {
-	bSize := *cmd.Flags().IntP("buffer-size", "b", 4 * 1024 * 1024, "buffer size, in bytes, per thread")
-	if bSize < 512 {
-		bSize = 512
+// requireFlags returns a UsageError if any of the flags provided was not set in the command line.
+func requireFlags(cmd *cobra.Command, flags ...string) error {
+	for _, flag := range flags {
+		if !cmd.Flags().Changed(flag) {
+			return usageErrorf("required flag \"%s\" not set", flag)
+		}
 	}
-	BufferSize = bSize
+	return nil
 }
 
-func addFlagNumberOfThreads(cmd *cobra.Command) {
-	threads := *cmd.Flags().IntP("threads", "t", runtime.NumCPU(), "number of threads in parallel operations")
+// checkThreads returns a UsageError if the number of threads provided is not valid.
+func checkThreads(threads int) error {
 	if threads < 1 {
-		ArgsErrors = append(ArgsErrors, errors.New("invalid number of threads"))
+		return usageErrorf("invalid number of threads: %d", threads)
+	}
+	return nil
+}
+
+// parseDate parses a date in the format YYYY-MM-DD or YYYY-MM-DD_hh-mm-ss in UTC.
+// If the date doesn't have time and endOfDay is true, it will return the last second of that day.
+// If the string is empty, it will return the zero time.
+func parseDate(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02_15-04-05", s, time.UTC); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
 	}
-	NumberOfThreads = threads
+	return t, nil
+}
+
+func addFlagAbortOnMismatch(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "abort-on-mismatch", false, "stop at the first restored file that doesn't match its hash")
+}
+
+func addFlagBackupName(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "name", "n", "", "backup name")
+}
+
+func addFlagBackupDate(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "date", "d", "", `date of the backup to restore.
+	It format must be YYYY-MM-DD_hh-mm-ss
+	and must match with the backup date.
+	If not provided, the latest backup will be restored.`)
+}
+
+func addFlagBufferSize(cmd *cobra.Command, p *int) {
+	cmd.Flags().IntVarP(p, "buffer-size", "b", 4*1024*1024, "buffer size, in bytes, per thread")
+}
+
+func addFlagCopyWorkers(cmd *cobra.Command, p *int) {
+	cmd.Flags().IntVar(p, "copy-workers", 0, "number of files copied to the repository at the same time (default: --threads)")
+}
+
+func addFlagDelete(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "delete", false, "delete the files of the destination that are not in the backup")
+}
+
+func addFlagDryRun(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "dry-run", false, "report what would be backed up without modifying the repository")
+}
+
+func addFlagExclude(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringSliceVar(p, "exclude", nil, "omit the files whose name matches this pattern (e.g. \"*.tmp\")")
+}
+
+func addFlagFilePath(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "path", "", "path of the file to restore inside the backup")
+}
+
+func addFlagFormat(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "format", "tar", "archive format (tar, tar.gz or zip)")
+}
+
+func addFlagFrom(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "from", "", "path of the repository to copy from")
+}
+
+func addFlagHash(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "hash", "", "only files whose hash starts with this one (in hexadecimal)")
+}
+
+func addFlagLargest(cmd *cobra.Command, p *int) {
+	cmd.Flags().IntVar(p, "largest", 10, "number of largest files to print")
+}
+
+func addFlagLogFile(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "log-file", "", "path of the file where the messages are appended, instead of the standard output")
+}
+
+func addFlagMaxSize(cmd *cobra.Command, p *int64) {
+	cmd.Flags().Int64Var(p, "max-size", 0, "only files of this size, in bytes, or smaller")
+}
+
+func addFlagMinSize(cmd *cobra.Command, p *int64) {
+	cmd.Flags().Int64Var(p, "min-size", 0, "only files of this size, in bytes, or bigger")
 }
 
-func addFlagOmitHidden(cmd *cobra.Command) {
-	cmd.Flags().BoolVar(&OmitHidden, "omit-hidden", false, "omit hidden files")
+func addFlagNumberOfThreads(cmd *cobra.Command, p *int) {
+	cmd.Flags().IntVarP(p, "threads", "t", runtime.NumCPU(), "number of threads in parallel operations")
 }
 
-func addFlagReadSymLinks(cmd *cobra.Command) {
-	cmd.Flags().BoolVar(&ReadSymLinks, "read-symlinks", false, "read symlinks (will not avoid infinite loops)")
+func addFlagOmitHidden(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "omit-hidden", false, "omit hidden files")
 }
 
-func addFlagSum(cmd *cobra.Command) {
-	sum := *cmd.Flags().StringP("sum", "s", "", `hash algorithm used in this repository. It cannot be changed later.
-Supported algorithms:
-    - MD5
-    - SHA1
-    - SHA256 (default)
-    - SHA512
-    - SHA3-256
-    - SHA3-512`)
-	if sum == "" {
-		sum = "sha256"
+func addFlagOnConflict(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "on-conflict", "skip", `what to do with the files that already exist in the destination
+    skip:         keep the existing file
+    overwrite:    replace the existing file
+    if-newer:     replace the existing file if it was modified before the one backed up
+                  (or before the backup was made, for backups without modification times)
+    if-different: replace the existing file if its size or hash are different
+    rename:       restore the file with the suffix ".restored-N"`)
+}
+
+func addFlagOutput(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "output", "o", "-", "path of the output file (\"-\" for standard output)")
+}
+
+func addFlagParanoid(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "paranoid", false, "compare byte by byte the files that are already in the repository (always enabled for md5 and sha1)")
+}
+
+func addFlagProfile(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "profile", "", "profile of the config file to use")
+}
+
+func addFlagRegex(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "regex", false, "use the pattern as a regular expression")
+}
+
+func addFlagRehash(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "rehash", false, "rehash the files if the repositories use different hash algorithms")
+}
+
+func addFlagSince(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "since", "", `only backups made in this date or after.
+	Its format must be YYYY-MM-DD or YYYY-MM-DD_hh-mm-ss`)
+}
+
+func addFlagSnapshots(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringSliceVar(p, "snapshot", nil, "only backups with this name or name/date")
+}
+
+func addFlagSources(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringSliceVar(p, "source", nil, "directory where to look for correct copies of the corrupted files")
+}
+
+func addFlagStdin(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "stdin", false, "back up the standard input as a single file instead of the paths provided")
+}
+
+func addFlagStdinFileName(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "stdin-filename", "stdin", "name of the file of the standard input in the backup")
+}
+
+func addFlagStatusFile(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "status-file", "", "path of the JSON file where the status of the jobs is written")
+}
+
+func addFlagSum(cmd *cobra.Command, p *string) {
+	const defaultSum = "sha256"
+	usage := bytes.NewBufferString("hash algorithm used in this repository. It cannot be changed later.\nSupported algorithms:")
+	for _, a := range hasher.Algorithms() {
+		_, _ = fmt.Fprintf(usage, "\n    - %s", a.Name)
+		if a.Name == defaultSum {
+			_, _ = usage.WriteString(" (default)")
+		}
+		if !a.Secure {
+			_, _ = usage.WriteString(" (not secure against collisions)")
+		}
 	}
-	Sum = sum
+	cmd.Flags().StringVarP(p, "sum", "s", defaultSum, usage.String())
+}
+
+func addFlagTo(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "to", "", "path of the repository to copy to")
+}
+
+func addFlagToAlgorithm(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "to", "", "hash algorithm to rehash the repository to")
+}
+
+func addFlagUntil(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "until", "", `only backups made in this date or before.
+	Its format must be YYYY-MM-DD or YYYY-MM-DD_hh-mm-ss`)
+}
+
+func addFlagVersion(cmd *cobra.Command, p *int) {
+	cmd.Flags().IntVar(p, "version", 0, "version of the file to restore (see \"gkup history\")")
+}
+
+func addFlagWatch(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "watch", false, "keep watching the paths and back up their changes until it's stopped")
+}
+
+func addFlagWatchInterval(cmd *cobra.Command, p *time.Duration) {
+	cmd.Flags().DurationVar(p, "watch-interval", time.Minute, "minimum time between the snapshots of --watch (e.g. \"30s\")")
 }
 
-func addPersistentFlagOmitErrors(cmd *cobra.Command) {
-	cmd.PersistentFlags().BoolVar(&OmitErrors, "omit-errors", false, "omit non-critical errors")
+func addPersistentFlagConfig(cmd *cobra.Command, p *string) {
+	cmd.PersistentFlags().StringVar(p, "config", "", `path of the config file
+    If not provided, GKUP_CONFIG or `+defaultConfigPath+` will be used`)
 }
 
-func addPersistentFlagRepoPath(cmd *cobra.Command) {
-	repoPath := *cmd.PersistentFlags().StringP("repo", "r", "", `path of your repository
+func addPersistentFlagJSON(cmd *cobra.Command, p *bool) {
+	cmd.PersistentFlags().BoolVar(p, "json", false, "write the results and errors in JSON, ending with a summary object")
+}
+
+func addPersistentFlagOmitErrors(cmd *cobra.Command, p *bool) {
+	cmd.PersistentFlags().BoolVar(p, "omit-errors", false, "omit non-critical errors")
+}
+
+func addPersistentFlagProgress(cmd *cobra.Command, p *string) {
+	cmd.PersistentFlags().StringVar(p, "progress", "none", `progress output of long-running operations, written to stderr
+    none:   no progress output
+    text:   human-readable progress line
+    ndjson: one JSON event per line`)
+}
+
+func addPersistentFlagRepoPath(cmd *cobra.Command, p *string) {
+	cmd.PersistentFlags().StringVarP(p, "repo", "r", "", `path of your repository
     If not provided, working directory will be used`)
-	if repoPath == "" {
-		repoPath = "."
-	}
-	RepoPath = repoPath
 }
 
-func addPersistentFlagVerboseLevel(cmd *cobra.Command) {
-	verbose := *cmd.PersistentFlags().IntP("verbose", "v", 2, `verbose level
+func addPersistentFlagVerboseLevel(cmd *cobra.Command, p *int) {
+	cmd.PersistentFlags().IntVarP(p, "verbose", "v", 2, `verbose level
     0: no output
     1: critical messages only
     2: critical and error messages
     3: detailed info and errors
     4: debug
    `)
-	if verbose < 0 || verbose > 4 {
-		ArgsErrors = append(ArgsErrors, errors.New("invalid verbose level"))
-	}
-	VerboseLevel = verbose
 }
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/daemon"
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/forget"
+	"github.com/Miguel-Dorta/gkup/pkg/schedule"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultConfigPath is the path of the config file used when none is provided
+const defaultConfigPath = "~/.config/gkup/config.toml"
+
+// profile is the profile of the config file selected in the command line
+var profile string
+
+// loadConfig reads the config file and applies the repository path that it, or the selected profile, defines
+// if it was not set in the command line. The values of the config file are read with the following precedence:
+// command line flags, selected profile, GKUP_* environment variables, config file and flag defaults.
+//
+// An example of config file:
+//
+//	repo = "/mnt/backups"
+//	threads = 4
+//	copy-workers = 8
+//	buffer-size = 8388608
+//	exclude = ["*.tmp", ".cache"]
+//
+//	[hooks]
+//	on-failure = "notify-send 'gkup failed' \"$GKUP_ERROR\""
+//	timeout = "5m"
+//	on-error = "abort"
+//
+//	[profile.db]
+//	repo = "/mnt/backups/db"
+//	paths = ["/var/backups/db"]
+//
+//	[profile.db.hooks]
+//	pre-backup = "pg_dump db > /var/backups/db/db.sql"
+//
+//	[daemon]
+//	log-file = "~/.local/state/gkup/daemon.log"
+//	status-file = "~/.local/state/gkup/status.json"
+//
+//	[job.hourly]
+//	repo = "/mnt/backups/home"
+//	paths = ["~/Documents"]
+//	name = "home"
+//	schedule = "0 * * * *"
+//	keep-last = 24
+//	keep-daily = 7
+//
+//	[job.verify]
+//	type = "check"
+//	repo = "/mnt/backups/home"
+//	schedule = "@weekly"
+//
+//	[profile.home]
+//	repo = "/mnt/backups/home"
+//	paths = ["~/Documents", "~/Pictures"]
+//	name = "home"
+//	exclude = ["node_modules"]
+func loadConfig(cmd *cobra.Command) error {
+	viper.SetEnvPrefix("gkup")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	if err := readConfigFile(); err != nil {
+		return err
+	}
+
+	if profile != "" && !viper.IsSet(profileKey("")) {
+		return fmt.Errorf("profile \"%s\" not found", profile)
+	}
+
+	configString(cmd, "repo", &Global.RepoPath)
+	if Global.RepoPath == "" {
+		Global.RepoPath = "."
+	}
+	Global.RepoPath = expandPath(Global.RepoPath)
+	return nil
+}
+
+// readConfigFile reads the config file from the path provided with --config or GKUP_CONFIG.
+// If none of them are provided, it will read the default config file if it exists.
+func readConfigFile() error {
+	path := Global.ConfigPath
+	if path == "" {
+		path = os.Getenv("GKUP_CONFIG")
+	}
+	if path == "" {
+		path = expandPath(defaultConfigPath)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	viper.SetConfigFile(expandPath(path))
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configString sets the string provided to the value of the config for the flag provided, if any (see configKey).
+func configString(cmd *cobra.Command, flag string, p *string) {
+	if key := configKey(cmd, flag); key != "" {
+		*p = viper.GetString(key)
+	}
+}
+
+// configInt sets the int provided to the value of the config for the flag provided, if any (see configKey).
+func configInt(cmd *cobra.Command, flag string, p *int) {
+	if key := configKey(cmd, flag); key != "" {
+		*p = viper.GetInt(key)
+	}
+}
+
+// configSectionString sets the string provided to the value of the section provided for the flag provided
+// if it's defined and the flag was not set in the command line.
+func configSectionString(cmd *cobra.Command, section, flag string, p *string) {
+	if !cmd.Flags().Changed(flag) && viper.IsSet(section+"."+flag) {
+		*p = viper.GetString(section + "." + flag)
+	}
+}
+
+// configProfileString sets the string provided to the value of the selected profile for the flag provided
+// if it's defined and the flag was not set in the command line.
+func configProfileString(cmd *cobra.Command, flag string, p *string) {
+	if profile != "" && !cmd.Flags().Changed(flag) && viper.IsSet(profileKey(flag)) {
+		*p = viper.GetString(profileKey(flag))
+	}
+}
+
+// configExclude appends to the patterns provided the ones defined in the config and in the selected profile,
+// and checks that all of them are valid.
+func configExclude(p *[]string) error {
+	*p = append(*p, viper.GetStringSlice("exclude")...)
+	if profile != "" {
+		*p = append(*p, viper.GetStringSlice(profileKey("exclude"))...)
+	}
+	for _, pattern := range *p {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern \"%s\": %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// configHooks returns the hooks defined in the config and in the section provided (a profile or a job),
+// whose values take precedence, or nil if there are none. Their output is written to the standard error.
+func configHooks(section string) (*hooks.Hooks, error) {
+	get := func(key string) string {
+		if section != "" && viper.IsSet(section+".hooks."+key) {
+			return viper.GetString(section + ".hooks." + key)
+		}
+		return viper.GetString("hooks." + key)
+	}
+
+	h := &hooks.Hooks{
+		PreBackup:  get(hooks.PreBackup),
+		PostBackup: get(hooks.PostBackup),
+		OnFailure:  get(hooks.OnFailure),
+		Output:     os.Stderr,
+	}
+	if h.PreBackup == "" && h.PostBackup == "" && h.OnFailure == "" {
+		return nil, nil
+	}
+
+	if timeout := get("timeout"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook timeout \"%s\": %w", timeout, err)
+		}
+		h.Timeout = d
+	}
+	if policy := get("on-error"); policy != "" {
+		p, err := hooks.ParsePolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		h.OnError = p
+	}
+	return h, nil
+}
+
+// configJobs returns the jobs defined in the job sections of the config, sorted by name, using the options provided
+// as the base of their options. Jobs without repository use the repository of the command line or the config.
+func configJobs(base *pkg.Options) ([]*daemon.Job, error) {
+	names := make([]string, 0, len(viper.GetStringMap("job")))
+	for name := range viper.GetStringMap("job") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	jobs := make([]*daemon.Job, 0, len(names))
+	for _, name := range names {
+		key := func(k string) string {
+			return "job." + name + "." + k
+		}
+
+		j := &daemon.Job{
+			Name:     name,
+			Type:     viper.GetString(key("type")),
+			RepoPath: viper.GetString(key("repo")),
+			Paths:    viper.GetStringSlice(key("paths")),
+			Snapshot: viper.GetString(key("name")),
+			Retention: forget.Policy{
+				KeepLast:    viper.GetInt(key("keep-last")),
+				KeepDaily:   viper.GetInt(key("keep-daily")),
+				KeepWeekly:  viper.GetInt(key("keep-weekly")),
+				KeepMonthly: viper.GetInt(key("keep-monthly")),
+			},
+		}
+		if j.Type == "" {
+			j.Type = daemon.JobBackup
+		}
+		if j.RepoPath == "" {
+			j.RepoPath = Global.RepoPath
+		}
+		j.RepoPath = expandPath(j.RepoPath)
+		for i := range j.Paths {
+			j.Paths[i] = expandPath(j.Paths[i])
+		}
+
+		var err error
+		if j.Schedule, err = schedule.Parse(viper.GetString(key("schedule"))); err != nil {
+			return nil, fmt.Errorf("job \"%s\": %w", name, err)
+		}
+		if within := viper.GetString(key("keep-within")); within != "" {
+			if j.Retention.KeepWithin, err = time.ParseDuration(within); err != nil {
+				return nil, fmt.Errorf("job \"%s\": invalid keep-within \"%s\": %w", name, within, err)
+			}
+		}
+
+		opts := *base
+		opts.Exclude = append(append([]string{}, base.Exclude...), viper.GetStringSlice(key("exclude"))...)
+		for _, pattern := range opts.Exclude {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("job \"%s\": invalid exclude pattern \"%s\": %w", name, pattern, err)
+			}
+		}
+		opts.OmitHidden = opts.OmitHidden || viper.GetBool(key("omit-hidden"))
+		opts.Paranoid = opts.Paranoid || viper.GetBool(key("paranoid"))
+		if opts.Hooks, err = configHooks("job." + name); err != nil {
+			return nil, fmt.Errorf("job \"%s\": %w", name, err)
+		}
+		j.Options = &opts
+
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// configProfilePaths returns the paths defined in the selected profile.
+func configProfilePaths() []string {
+	if profile == "" {
+		return nil
+	}
+
+	paths := viper.GetStringSlice(profileKey("paths"))
+	for i := range paths {
+		paths[i] = expandPath(paths[i])
+	}
+	return paths
+}
+
+// configKey returns the key from where the value of the flag provided must be read.
+// It will return an empty string if the flag was set in the command line, or if it's not set
+// neither in the selected profile nor in the environment or config file.
+func configKey(cmd *cobra.Command, flag string) string {
+	if cmd.Flags().Changed(flag) {
+		return ""
+	}
+	if profile != "" && viper.IsSet(profileKey(flag)) {
+		return profileKey(flag)
+	}
+	if viper.IsSet(flag) {
+		return flag
+	}
+	return ""
+}
+
+// profileKey returns the key provided inside the selected profile
+func profileKey(key string) string {
+	if key == "" {
+		return "profile." + profile
+	}
+	return "profile." + profile + "." + key
+}
+
+// expandPath expands the leading "~" of the path provided, if any
+func expandPath(path string) string {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return path
+	}
+	return expanded
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/copy"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// copyOptions represents the options of the copy command
+type copyOptions struct {
+	BufferSize int
+	From       string
+	Rehash     bool
+	To         string
+}
+
+var copyOpts copyOptions
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy [snapshots]",
+	Short: "Copy backups between repositories",
+	Long: `copy will copy the backups that match the names or name/date provided (or all
+of them if none is provided) from a repository to another. Only the files that
+don't exist in the destination will be copied, and their hashes will be verified
+during the copy.`,
+	RunE: runCopy,
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+
+	addFlagBufferSize(copyCmd, &copyOpts.BufferSize)
+	addFlagFrom(copyCmd, &copyOpts.From)
+	addFlagRehash(copyCmd, &copyOpts.Rehash)
+	addFlagTo(copyCmd, &copyOpts.To)
+}
+
+func runCopy(cmd *cobra.Command, args []string) error {
+	if err := requireFlags(cmd, "from", "to"); err != nil {
+		return err
+	}
+	configInt(cmd, "buffer-size", &copyOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = copyOpts.BufferSize
+
+	return copy.Copy(ctx, expandPath(copyOpts.From), expandPath(copyOpts.To), args, copyOpts.Rehash, opts, Global.JSON, os.Stdout)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/daemon"
+	"github.com/Miguel-Dorta/logolang"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// daemonOptions represents the options of the daemon command
+type daemonOptions struct {
+	BufferSize  int
+	CopyWorkers int
+	LogFile     string
+	StatusFile  string
+	Threads     int
+}
+
+var daemonOpts daemonOptions
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the jobs of the config file on schedule",
+	Long: `daemon runs the backups and checks defined in the [job.<name>] sections of the
+config file on their schedule until it's stopped. The jobs of the same
+repository are run one at a time, and a job that is due while it's still
+running is skipped.
+
+Each job can define:
+    type:         "backup" (default) or "check"
+    repo:         repository path, the global one if not defined
+    paths:        paths to back up
+    name:         name of the snapshots created
+    schedule:     cron-style schedule (e.g. "0 * * * *" or "@daily")
+    exclude:      patterns to exclude, in addition to the global ones
+    omit-hidden:  omit hidden files
+    paranoid:     compare byte by byte the files already in the repository
+    keep-last, keep-daily, keep-weekly, keep-monthly, keep-within:
+                  retention policy applied to the snapshots of the job after
+                  each backup. The snapshots removed and the objects that are
+                  not referenced anymore are deleted
+    hooks:        hooks of the backups, overriding the global ones
+
+The status of every job (next run, last run and its result) is written as JSON
+in the status file after every change.`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	addFlagBufferSize(daemonCmd, &daemonOpts.BufferSize)
+	addFlagCopyWorkers(daemonCmd, &daemonOpts.CopyWorkers)
+	addFlagLogFile(daemonCmd, &daemonOpts.LogFile)
+	addFlagNumberOfThreads(daemonCmd, &daemonOpts.Threads)
+	addFlagStatusFile(daemonCmd, &daemonOpts.StatusFile)
+}
+
+func runDaemon(cmd *cobra.Command, _ []string) error {
+	configInt(cmd, "buffer-size", &daemonOpts.BufferSize)
+	configInt(cmd, "threads", &daemonOpts.Threads)
+	configInt(cmd, "copy-workers", &daemonOpts.CopyWorkers)
+	configSectionString(cmd, "daemon", "log-file", &daemonOpts.LogFile)
+	configSectionString(cmd, "daemon", "status-file", &daemonOpts.StatusFile)
+	if err := checkThreads(daemonOpts.Threads); err != nil {
+		return err
+	}
+
+	opts := pkg.NewOptions()
+	opts.BufferSize = daemonOpts.BufferSize
+	opts.CopyWorkers = daemonOpts.CopyWorkers
+	opts.NumberOfThreads = daemonOpts.Threads
+	if err := configExclude(&opts.Exclude); err != nil {
+		return &UsageError{Err: err}
+	}
+	jobs, err := configJobs(opts)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+	if len(jobs) == 0 {
+		return usageErrorf("no jobs defined in the config file")
+	}
+
+	log := pkg.Log
+	if daemonOpts.LogFile != "" {
+		f, err := os.OpenFile(expandPath(daemonOpts.LogFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, pkg.DefaultFilePerm)
+		if err != nil {
+			return &os.PathError{
+				Op:   "open log file",
+				Path: daemonOpts.LogFile,
+				Err:  err,
+			}
+		}
+		defer f.Close()
+
+		w := &logolang.SafeWriter{W: f}
+		log = logolang.NewLoggerWriters(w, w, w, w)
+		log.Color = false
+		log.Formatter = pkg.Log.Formatter
+		log.Level = pkg.Log.Level
+	}
+
+	d, err := daemon.New(jobs, expandPath(daemonOpts.StatusFile), log)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+	return d.Run(ctx)
+}
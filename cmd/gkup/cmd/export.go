@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/export"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+// exportOptions represents the options of the export command
+type exportOptions struct {
+	BufferSize int
+	Format     string
+	Output     string
+}
+
+var exportOpts exportOptions
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <snapshot>",
+	Short: "Export a backup as an archive",
+	Long: `export will write the backup that matches the name or name/date provided as
+a tar, tar.gz or zip archive, reading the files directly from the repository.
+If only the name is provided, the latest backup with that name will be exported.`,
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	addFlagBufferSize(exportCmd, &exportOpts.BufferSize)
+	addFlagFormat(exportCmd, &exportOpts.Format)
+	addFlagOutput(exportCmd, &exportOpts.Output)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("one backup to export must be provided")
+	}
+	if exportOpts.Output == "-" && Global.JSON {
+		return usageErrorf("an output file must be provided when using JSON output")
+	}
+	configInt(cmd, "buffer-size", &exportOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = exportOpts.BufferSize
+
+	var output io.WriteCloser = os.Stdout
+	if exportOpts.Output != "-" {
+		f, err := os.Create(exportOpts.Output)
+		if err != nil {
+			return &os.PathError{
+				Op:   "create output file",
+				Path: exportOpts.Output,
+				Err:  err,
+			}
+		}
+		output = f
+	}
+
+	if err := export.Export(ctx, Global.RepoPath, args[0], exportOpts.Format, opts, output); err != nil {
+		if output != os.Stdout {
+			_ = output.Close()
+			_ = os.Remove(exportOpts.Output)
+		}
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return &os.PathError{
+			Op:   "close output file",
+			Path: exportOpts.Output,
+			Err:  err,
+		}
+	}
+	return nil
+}
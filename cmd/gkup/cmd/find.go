@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/find"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// findOptions represents the options of the find command
+type findOptions struct {
+	Hash      string
+	MaxSize   int64
+	MinSize   int64
+	Regex     bool
+	Since     string
+	Snapshots []string
+	Until     string
+}
+
+var findOpts findOptions
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find <pattern>",
+	Short: "Find files in the backups of your repository",
+	Long: `find will print the files of the backups that match the pattern provided, along
+with the backup where they are, their size, and the date of the backup.
+The pattern is a glob pattern that will be matched against the file names, or
+against their paths if it contains a '/'. If --regex is provided, it will be a
+regular expression that will be matched against their paths.`,
+	RunE: runFind,
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+
+	addFlagHash(findCmd, &findOpts.Hash)
+	addFlagMaxSize(findCmd, &findOpts.MaxSize)
+	addFlagMinSize(findCmd, &findOpts.MinSize)
+	addFlagRegex(findCmd, &findOpts.Regex)
+	addFlagSince(findCmd, &findOpts.Since)
+	addFlagSnapshots(findCmd, &findOpts.Snapshots)
+	addFlagUntil(findCmd, &findOpts.Until)
+}
+
+func runFind(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("one pattern to find must be provided")
+	}
+
+	filter := find.Filter{
+		MinSize: findOpts.MinSize,
+		MaxSize: findOpts.MaxSize,
+	}
+	var err error
+	if filter.Hash, err = hex.DecodeString(findOpts.Hash); err != nil {
+		return usageErrorf("invalid hash: %w", err)
+	}
+	if filter.Since, err = parseDate(findOpts.Since, false); err != nil {
+		return usageErrorf("invalid date in --since: %w", err)
+	}
+	if filter.Until, err = parseDate(findOpts.Until, true); err != nil {
+		return usageErrorf("invalid date in --until: %w", err)
+	}
+
+	return find.Find(Global.RepoPath, args[0], findOpts.Regex, findOpts.Snapshots, filter, Global.JSON, os.Stdout)
+}
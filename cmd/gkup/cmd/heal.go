@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/heal"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// healOptions represents the options of the heal command
+type healOptions struct {
+	BufferSize int
+	Sources    []string
+}
+
+var healOpts healOptions
+
+// healCmd represents the heal command
+var healCmd = &cobra.Command{
+	Use:   "heal",
+	Short: "Quarantine the corrupted files of your repository and repair them",
+	Long: `heal will check the integrity of the files in your repository, move the corrupted
+ones to the quarantine folder, and list the backups that referenced them.
+If source directories are provided, it will look for a correct copy of the
+corrupted files in them and add it back to the repository.
+Files that cannot be read are reported and left in place.`,
+	RunE: runHeal,
+}
+
+func init() {
+	rootCmd.AddCommand(healCmd)
+
+	addFlagBufferSize(healCmd, &healOpts.BufferSize)
+	addFlagSources(healCmd, &healOpts.Sources)
+}
+
+func runHeal(cmd *cobra.Command, _ []string) error {
+	configInt(cmd, "buffer-size", &healOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = healOpts.BufferSize
+
+	return heal.Heal(ctx, Global.RepoPath, healOpts.Sources, opts, Global.JSON, os.Stdout)
+}
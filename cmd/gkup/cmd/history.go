@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/history"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// historyOptions represents the options of the history command
+type historyOptions struct {
+	Name string
+}
+
+var historyOpts historyOptions
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <path>",
+	Short: "Print the versions of a file across the backups",
+	Long: `history will walk all the backups with the name provided in chronological order
+and print every distinct version of the file of the path provided, with the first
+and the last backup where it appears.
+A version can be restored with "gkup restore --path <path> --version <version>".`,
+	RunE: runHistory,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	addFlagBackupName(historyCmd, &historyOpts.Name)
+}
+
+func runHistory(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("one path must be provided")
+	}
+
+	return history.History(Global.RepoPath, historyOpts.Name, args[0], Global.JSON, os.Stdout)
+}
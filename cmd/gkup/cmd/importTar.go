@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/importTar"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// importTarOptions represents the options of the import-tar command
+type importTarOptions struct {
+	BufferSize int
+	Name       string
+}
+
+var importTarOpts importTarOptions
+
+// importTarCmd represents the import-tar command
+var importTarCmd = &cobra.Command{
+	Use:   "import-tar <archive|->",
+	Short: "Create a new backup from a tar archive",
+	Long: `import-tar will read the tar archive provided (optionally compressed with gzip),
+or the standard input if "-" is provided, add its files to the repository, and
+create a new backup with the current date and the name provided that reflects
+the tree of the archive.`,
+	RunE: runImportTar,
+}
+
+func init() {
+	rootCmd.AddCommand(importTarCmd)
+
+	addFlagBackupName(importTarCmd, &importTarOpts.Name)
+	addFlagBufferSize(importTarCmd, &importTarOpts.BufferSize)
+}
+
+func runImportTar(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("one archive to import must be provided")
+	}
+	configInt(cmd, "buffer-size", &importTarOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = importTarOpts.BufferSize
+
+	input := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return &os.PathError{
+				Op:   "open archive",
+				Path: args[0],
+				Err:  err,
+			}
+		}
+		defer f.Close()
+		input = f
+	}
+
+	return importTar.Import(ctx, Global.RepoPath, importTarOpts.Name, input, opts, Global.JSON, os.Stdout)
+}
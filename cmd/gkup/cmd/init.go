@@ -1,19 +1,31 @@
 package cmd
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
 	"github.com/spf13/cobra"
 )
 
+// initOptions represents the options of the init command
+type initOptions struct {
+	Sum string
+}
+
+var initOpts initOptions
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize repository where backups will be stored",
 	Long:  `init initializes the gkup repository where backups will be stored.`,
-	Run:   parseCmd,
+	RunE:  runInit,
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
 
-	addFlagSum(initCmd)
+	addFlagSum(initCmd, &initOpts.Sum)
+}
+
+func runInit(_ *cobra.Command, _ []string) error {
+	return create.Create(Global.RepoPath, initOpts.Sum)
 }
@@ -1,17 +1,23 @@
 package cmd
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/list"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List the backups saved in the repo",
-	Long: `Print a list of the backups saved in the repository provided.`,
-	Run: parseCmd,
+	Long:  `Print a list of the backups saved in the repository provided.`,
+	RunE:  runList,
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 }
+
+func runList(_ *cobra.Command, _ []string) error {
+	return list.List(Global.RepoPath, Global.JSON, os.Stdout)
+}
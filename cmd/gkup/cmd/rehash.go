@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/rehash"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// rehashOptions represents the options of the rehash command
+type rehashOptions struct {
+	BufferSize int
+	To         string
+}
+
+var rehashOpts rehashOptions
+
+// rehashCmd represents the rehash command
+var rehashCmd = &cobra.Command{
+	Use:   "rehash",
+	Short: "Change the hash algorithm of your repository",
+	Long: `rehash will hash again all the files of the repository with the algorithm
+provided, rename them and update all the backups to use the new hashes. If it's
+interrupted, running it again with the same algorithm will resume it. Once it's
+finished, the integrity of the repository will be verified.`,
+	RunE: runRehash,
+}
+
+func init() {
+	rootCmd.AddCommand(rehashCmd)
+
+	addFlagBufferSize(rehashCmd, &rehashOpts.BufferSize)
+	addFlagToAlgorithm(rehashCmd, &rehashOpts.To)
+}
+
+func runRehash(cmd *cobra.Command, _ []string) error {
+	if err := requireFlags(cmd, "to"); err != nil {
+		return err
+	}
+	configInt(cmd, "buffer-size", &rehashOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = rehashOpts.BufferSize
+
+	return rehash.Rehash(ctx, Global.RepoPath, rehashOpts.To, opts, Global.JSON, os.Stdout)
+}
@@ -1,25 +1,101 @@
 package cmd
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/history"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/restore"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
 	"github.com/spf13/cobra"
+	"os"
 )
 
+// restoreOptions represents the options of the restore command
+type restoreOptions struct {
+	AbortOnMismatch bool
+	BufferSize      int
+	Date            string
+	Delete          bool
+	FilePath        string
+	Name            string
+	OnConflict      string
+	Version         int
+}
+
+var restoreOpts restoreOptions
+
 // restoreCmd represents the restore command
 var restoreCmd = &cobra.Command{
-	Use:   "restore",
+	Use:   "restore <destination>",
 	Short: "Restore a backup in a directory.",
 	Long: `Restore a backup that matches the given name (if provided) and date in the
-directory specified.`,
-	Run: parseCmd,
+directory specified. If no date is provided, the latest backup with that name
+will be restored. The files that already exist in the destination are handled
+according to --on-conflict, and --delete removes the ones that are not in the
+backup, so the destination becomes a mirror of it.
+Every file is verified while it's restored. The ones that don't match the backup
+are removed and reported, and the command fails after restoring the rest of them,
+or immediately if --abort-on-mismatch is provided.
+If a path and a version are provided instead of a date, that version of the file
+will be restored (see "gkup history").`,
+	RunE: runRestore,
 }
 
 func init() {
 	rootCmd.AddCommand(restoreCmd)
 
-	addFlagBackupName(restoreCmd)
-	addFlagBufferSize(restoreCmd)
-	addFlagBackupDate(restoreCmd)
-	if err := restoreCmd.MarkFlagRequired("date"); err != nil {
-		panic(err)
+	addFlagAbortOnMismatch(restoreCmd, &restoreOpts.AbortOnMismatch)
+	addFlagBackupName(restoreCmd, &restoreOpts.Name)
+	addFlagBufferSize(restoreCmd, &restoreOpts.BufferSize)
+	addFlagBackupDate(restoreCmd, &restoreOpts.Date)
+	addFlagDelete(restoreCmd, &restoreOpts.Delete)
+	addFlagOnConflict(restoreCmd, &restoreOpts.OnConflict)
+	addFlagFilePath(restoreCmd, &restoreOpts.FilePath)
+	addFlagVersion(restoreCmd, &restoreOpts.Version)
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("destination path not provided")
+	}
+	if len(args) > 1 {
+		return usageErrorf("more than one path to restore provided")
+	}
+	configInt(cmd, "buffer-size", &restoreOpts.BufferSize)
+	opts := pkg.NewOptions()
+	opts.BufferSize = restoreOpts.BufferSize
+
+	if restoreOpts.Version != 0 {
+		if restoreOpts.FilePath == "" {
+			return usageErrorf("path of the file to restore not provided")
+		}
+		if cmd.Flags().Changed("delete") || cmd.Flags().Changed("on-conflict") {
+			return usageErrorf("--delete and --on-conflict cannot be used when restoring a version of a file")
+		}
+		return history.RestoreVersion(ctx, Global.RepoPath, restoreOpts.Name, restoreOpts.FilePath, restoreOpts.Version, args[0], opts)
 	}
+
+	selector := restoreOpts.Name
+	if restoreOpts.Date != "" {
+		id, err := snapshots.ParseID(restoreOpts.Date)
+		if err != nil || id.Name != "" {
+			return usageErrorf("invalid backup date: %s", restoreOpts.Date)
+		}
+		id.Name = restoreOpts.Name
+		selector = id.String()
+	}
+
+	policy, err := restore.ParsePolicy(restoreOpts.OnConflict)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+	mode := restore.Mode{
+		OnConflict:      policy,
+		Delete:          restoreOpts.Delete,
+		AbortOnMismatch: restoreOpts.AbortOnMismatch,
+	}
+	if policy == restore.ConflictRename && mode.Delete {
+		return usageErrorf("--on-conflict=rename cannot be used with --delete")
+	}
+
+	return restore.Restore(ctx, Global.RepoPath, selector, args[0], mode, opts, Global.JSON, os.Stdout)
 }
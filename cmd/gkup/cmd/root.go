@@ -1,8 +1,36 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/logolang"
 	"github.com/spf13/cobra"
+	"os"
+)
+
+// GlobalOptions represents the options shared by all the commands.
+type GlobalOptions struct {
+	ConfigPath string
+	JSON       bool
+	OmitErrors bool
+	Progress   string
+	RepoPath   string
+	Verbose    int
+}
+
+var (
+	// Cmd is the name of the command executed.
+	Cmd string
+	// Global are the options shared by all the commands.
+	Global GlobalOptions
+
+	// ctx is the context of the command executed.
+	ctx = context.Background()
+	// ready is whether the command line was parsed and validated, so the command can run.
+	ready bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -18,17 +46,85 @@ restore even if all the copies of this program are erased of the surface of
 the Earth, and they'll also easily parseable by other programs.
 
 gkup is not aimed to provide any kind of compression, encryption or redundancy.
-It's the user's' responsibility to do this if they feel they wanted.`,
-	Version: internal.Version,
+It's the user's' responsibility to do this if they feel they wanted.
+
+Exit status:
+    0:   success
+    1:   the operation failed
+    2:   invalid command line or config file
+    130: the operation was interrupted`,
+	Version:           internal.Version,
+	PersistentPreRunE: setup,
+	SilenceErrors:     true,
+	SilenceUsage:      true,
 }
 
-// Parse adds all child commands to the root command and sets flags appropriately.
-func Parse() error {
-	return rootCmd.Execute()
+// Execute parses the command line and runs the command provided with the context provided.
+// The errors caused by an invalid command line or config file are returned as a UsageError.
+func Execute(c context.Context) error {
+	ctx = c
+	executed, err := rootCmd.ExecuteC()
+	if executed != nil && Cmd == "" {
+		Cmd = executed.Name()
+	}
+
+	var usageErr *UsageError
+	if err != nil && !ready && !errors.As(err, &usageErr) {
+		return &UsageError{Err: err}
+	}
+	return err
 }
 
 func init() {
-	addPersistentFlagRepoPath(rootCmd)
-	addPersistentFlagVerboseLevel(rootCmd)
-	addPersistentFlagOmitErrors(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &UsageError{Err: err}
+	})
+
+	addPersistentFlagConfig(rootCmd, &Global.ConfigPath)
+	addPersistentFlagRepoPath(rootCmd, &Global.RepoPath)
+	addPersistentFlagVerboseLevel(rootCmd, &Global.Verbose)
+	addPersistentFlagJSON(rootCmd, &Global.JSON)
+	addPersistentFlagOmitErrors(rootCmd, &Global.OmitErrors)
+	addPersistentFlagProgress(rootCmd, &Global.Progress)
+}
+
+// setup loads the config file, validates the global options and applies them.
+// It's run after the command line is parsed and before any command.
+func setup(cmd *cobra.Command, _ []string) error {
+	Cmd = cmd.Name()
+
+	if err := loadConfig(cmd); err != nil {
+		return &UsageError{Err: err}
+	}
+
+	if Global.Verbose < 0 || Global.Verbose > 4 {
+		return usageErrorf("invalid verbose level: %d", Global.Verbose)
+	}
+	pkg.Log.Level = Global.Verbose
+	if Global.JSON {
+		useStderrLog()
+	}
+
+	switch Global.Progress {
+	case "none":
+	case "text":
+		pkg.Progress = progress.NewTextRenderer(os.Stderr)
+	case "ndjson":
+		pkg.Progress = progress.NewNDJSONRenderer(os.Stderr)
+	default:
+		return usageErrorf("invalid progress output: %s", Global.Progress)
+	}
+
+	pkg.OmitErrors = Global.OmitErrors
+	ready = true
+	return nil
+}
+
+// useStderrLog makes the logger write every level in stderr without colors, so stdout only contains JSON.
+func useStderrLog() {
+	l := logolang.NewLoggerWriters(os.Stderr, os.Stderr, os.Stderr, os.Stderr)
+	l.Color = false
+	l.Formatter = pkg.Log.Formatter
+	l.Level = pkg.Log.Level
+	pkg.Log = l
 }
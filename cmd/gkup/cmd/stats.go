@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/stats"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// statsOptions represents the options of the stats command
+type statsOptions struct {
+	Largest int
+}
+
+var statsOpts statsOptions
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats [snapshots]",
+	Short: "Print statistics of your repository",
+	Long: `stats will print the number and size of the files stored in the repository, and
+the logical size, the size added and the deduplication ratio of the backups that
+match the names or name/date provided (or all of them if none is provided).`,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	addFlagLargest(statsCmd, &statsOpts.Largest)
+}
+
+func runStats(_ *cobra.Command, args []string) error {
+	if statsOpts.Largest < 0 {
+		return usageErrorf("invalid number of largest files: %d", statsOpts.Largest)
+	}
+	return stats.Stats(Global.RepoPath, args, statsOpts.Largest, Global.JSON, os.Stdout)
+}
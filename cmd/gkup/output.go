@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Miguel-Dorta/gkup/cmd/gkup/cmd"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"os"
+)
+
+// Exit status of the process
+const (
+	exitOK          = 0
+	exitFailed      = 1   // The operation failed
+	exitUsage       = 2   // The command line or the config file is not valid
+	exitInterrupted = 130 // The operation was interrupted by a signal
+)
+
+// Values of the field "status" of summaryJSON
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// summaryJSON is the last JSON object that every command writes to stdout when the flag --json is used.
+// The objects written before it, if any, are the results of the command, whose schema is documented
+// in the report types of its action. All of them are written one per line.
+// The command check writes an object like this for every file that fails the check:
+//
+//	{"type":"error","error":"hashes don't match in file ..."}
+//
+// Example:
+//
+//	{"type":"summary","command":"backup","status":"error","exit_code":1,"error":"error reading settings: ..."}
+type summaryJSON struct {
+	Type     string `json:"type"`            // Always "summary"
+	Command  string `json:"command"`         // Name of the command executed
+	Status   string `json:"status"`          // "ok" or "error"
+	ExitCode int    `json:"exit_code"`       // Exit status of the process
+	Error    string `json:"error,omitempty"` // Error that made the command fail, if any
+}
+
+// exitSuccess finishes the execution successfully, writing the summary if the output is in JSON.
+func exitSuccess() {
+	if cmd.Global.JSON {
+		writeSummary(exitOK, "")
+	}
+	os.Exit(exitOK)
+}
+
+// exitWithError reports the error provided and finishes the execution with the exit status that corresponds to it.
+func exitWithError(err error) {
+	exitCode := exitFailed
+	var usageErr *cmd.UsageError
+	if errors.As(err, &usageErr) {
+		exitCode = exitUsage
+	} else if errors.Is(err, context.Canceled) {
+		exitCode = exitInterrupted
+	}
+
+	if cmd.Global.JSON {
+		writeSummary(exitCode, err.Error())
+	} else {
+		pkg.Log.Critical(err.Error())
+	}
+	os.Exit(exitCode)
+}
+
+// writeSummary writes to stdout the summary of the command with the exit code and the error message provided.
+func writeSummary(exitCode int, errMsg string) {
+	summary := summaryJSON{
+		Type:     "summary",
+		Command:  cmd.Cmd,
+		Status:   statusOK,
+		ExitCode: exitCode,
+		Error:    errMsg,
+	}
+	if exitCode != exitOK {
+		summary.Status = statusError
+	}
+
+	data, _ := json.Marshal(summary)
+	_, _ = os.Stdout.Write(append(data, '\n'))
+}
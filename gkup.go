@@ -0,0 +1,175 @@
+// Package gkup provides access to gkup repositories from Go programs.
+//
+// A repository is opened with Open, or created with Create, and the operations available in the command line
+// are methods of the Repository returned. All of them return typed results instead of writing formatted output.
+//
+//	repo, err := gkup.Open("/mnt/backups")
+//	if err != nil {
+//		return err
+//	}
+//	report, err := repo.Backup(ctx, "home", []string{"/home/user"}, &gkup.Options{NumberOfThreads: 4})
+//
+// The operations that accept an Options use the defaults of the package pkg for the nil ones
+// and the zero values that are not valid (see pkg.Options.Normalize).
+package gkup
+
+import (
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/backup"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/restore"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/stats"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// Conflict policies of RestoreMode
+const (
+	ConflictSkip        = restore.ConflictSkip
+	ConflictOverwrite   = restore.ConflictOverwrite
+	ConflictIfNewer     = restore.ConflictIfNewer
+	ConflictIfDifferent = restore.ConflictIfDifferent
+	ConflictRename      = restore.ConflictRename
+)
+
+// StatsLargestFiles is the number of largest files included in the report returned by Repository.Stats.
+const StatsLargestFiles = 10
+
+type (
+	// Options represents the settings of an operation.
+	Options = pkg.Options
+	// Hooks represents the commands run around a backup (see Options.Hooks).
+	Hooks = hooks.Hooks
+
+	// BackupReport represents the result of Repository.Backup.
+	BackupReport = backup.Report
+	// RestoreMode represents how Repository.Restore handles a destination that is not empty.
+	// Its zero value skips the files that already exist.
+	RestoreMode = restore.Mode
+	// ConflictPolicy represents what Repository.Restore does with the files that already exist.
+	ConflictPolicy = restore.Policy
+	// RestoreReport represents the result of Repository.Restore.
+	RestoreReport = restore.Report
+	// CheckReport represents the result of Repository.Check.
+	CheckReport = check.Report
+	// StatsReport represents the result of Repository.Stats.
+	StatsReport = stats.Report
+)
+
+// Repository represents a gkup repository.
+type Repository struct {
+	path          string
+	hashAlgorithm string
+}
+
+// Open opens the repository of the path provided.
+func Open(path string) (*Repository, error) {
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+	if _, err := hasher.GetAlgorithm(sett.HashAlgorithm); err != nil {
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+
+	return &Repository{
+		path:          path,
+		hashAlgorithm: sett.HashAlgorithm,
+	}, nil
+}
+
+// Create creates a repository in the path provided that will use the hash algorithm provided, and opens it.
+// The path must not exist or be an empty directory.
+func Create(path, hashAlgorithm string) (*Repository, error) {
+	if err := create.Create(path, hashAlgorithm); err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+// Path returns the path of the repository.
+func (r *Repository) Path() string {
+	return r.path
+}
+
+// HashAlgorithm returns the name of the hash algorithm that the repository uses.
+func (r *Repository) HashAlgorithm() string {
+	return r.hashAlgorithm
+}
+
+// Snapshots returns all the snapshots of the repository, sorted by name and time.
+func (r *Repository) Snapshots() ([]*Snapshot, error) {
+	ids, err := snapshots.List(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Snapshot, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, newSnapshot(r.path, id))
+	}
+	return list, nil
+}
+
+// Snapshot returns the latest snapshot of the repository that matches the selector provided.
+// A selector can be a snapshot ID, in the format "[name/]YYYY-MM-DD_hh-mm-ss", or a snapshot name.
+func (r *Repository) Snapshot(selector string) (*Snapshot, error) {
+	id, err := snapshots.Find(r.path, selector)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(r.path, id), nil
+}
+
+// Backup stores the files of the paths provided in the repository and creates a snapshot with the name provided
+// that reflects their tree. See backup.Backup for the details.
+func (r *Repository) Backup(ctx context.Context, name string, paths []string, opts *Options) (*BackupReport, error) {
+	return backup.Run(ctx, r.path, name, paths, opts)
+}
+
+// BackupStream stores the content of the reader provided in the repository and creates a snapshot with the name
+// provided that contains it as a single file with the file name provided. See backup.Stdin for the details.
+func (r *Repository) BackupStream(ctx context.Context, name, fileName string, content io.Reader, opts *Options) (*BackupReport, error) {
+	return backup.RunStdin(ctx, r.path, name, fileName, content, opts)
+}
+
+// EstimateBackup is like Backup, but it doesn't modify the repository. It returns how many files would be stored
+// and which ones would be skipped. See backup.DryRun for the details.
+func (r *Repository) EstimateBackup(ctx context.Context, name string, paths []string, opts *Options) (*BackupReport, error) {
+	return backup.Estimate(ctx, r.path, name, paths, opts)
+}
+
+// WatchBackup is like Backup, but it keeps watching the paths provided and creates a new snapshot with their changes
+// at most every interval provided, calling the function provided with its report, until the context provided is cancelled.
+// See backup.Watch for the details.
+func (r *Repository) WatchBackup(ctx context.Context, name string, paths []string, interval time.Duration, opts *Options, onSnapshot func(*BackupReport) error) error {
+	return backup.RunWatch(ctx, r.path, name, paths, interval, opts, onSnapshot)
+}
+
+// Restore restores the latest snapshot that matches the selector provided (see Repository.Snapshot)
+// in the destination provided, handling its existing files according to the mode provided.
+// The files restored are verified. If some of them don't match the snapshot, it returns the report
+// along with an error wrapping files.ErrMismatch. See restore.Restore for the details.
+func (r *Repository) Restore(ctx context.Context, selector, destination string, mode RestoreMode, opts *Options) (*RestoreReport, error) {
+	return restore.Run(ctx, r.path, selector, destination, mode, opts)
+}
+
+// Check checks the integrity of all the files stored in the repository and returns the ones that failed.
+// The failures found are not considered an error.
+func (r *Repository) Check(ctx context.Context, opts *Options) (*CheckReport, error) {
+	return check.FindFailures(ctx, r.path, opts)
+}
+
+// Stats returns statistics about all the snapshots and the objects of the repository,
+// including its StatsLargestFiles largest files.
+func (r *Repository) Stats() (*StatsReport, error) {
+	return stats.GetReport(r.path, nil, StatsLargestFiles)
+}
@@ -0,0 +1,164 @@
+package gkup_test
+
+import (
+	"context"
+	"github.com/Miguel-Dorta/gkup"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	opts        = &gkup.Options{BufferSize: 512, NumberOfThreads: 2}
+	testingPath = filepath.Join(os.TempDir(), "gkup_TestRepository")
+	repoPath    = filepath.Join(testingPath, "repo")
+	srcPath     = filepath.Join(testingPath, "src")
+)
+
+var srcFiles = map[string]string{
+	"a":         "first content",
+	"dir/b":     "second content",
+	"dir/c":     "first content",
+	"dir/sub/d": "third content",
+}
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestRepository(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+
+	if _, err := gkup.Open(repoPath); err == nil {
+		t.Fatal("expected error opening a repo that doesn't exist")
+	}
+	repo, err := gkup.Create(repoPath, "sha256")
+	if err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	if repo, err = gkup.Open(repoPath); err != nil {
+		t.Fatalf("error opening repo: %s", err)
+	}
+	if repo.HashAlgorithm() != "sha256" {
+		t.Errorf("unexpected hash algorithm: %s", repo.HashAlgorithm())
+	}
+
+	// Backup
+	backupReport, err := repo.Backup(context.Background(), "test", []string{srcPath}, opts)
+	if err != nil {
+		t.Fatalf("error backing up files: %s", err)
+	}
+	if backupReport.Files != 4 || backupReport.NewFiles != 3 {
+		t.Errorf("unexpected backup report: %+v", backupReport)
+	}
+
+	// Snapshots
+	list, err := repo.Snapshots()
+	if err != nil {
+		t.Fatalf("error listing snapshots: %s", err)
+	}
+	if len(list) != 1 || list[0].ID() != backupReport.Snapshot || list[0].Name != "test" {
+		t.Fatalf("unexpected snapshots: %+v", list)
+	}
+	snap, err := repo.Snapshot("test")
+	if err != nil {
+		t.Fatalf("error finding snapshot: %s", err)
+	}
+	if incomplete, err := snap.Incomplete(); err != nil || incomplete {
+		t.Errorf("unexpected incomplete snapshot: %t, %v", incomplete, err)
+	}
+	walked := make(map[string]bool, len(srcFiles))
+	err = snap.Walk(func(path string, f gkup.File) error {
+		data, err := ioutil.ReadFile(f.ObjectPath)
+		if err != nil {
+			return err
+		}
+		expected := srcFiles[path[len("src/"):]]
+		if string(data) != expected || f.Size != int64(len(expected)) {
+			t.Errorf("unexpected content of %s: %s", path, data)
+		}
+		walked[path] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error walking snapshot: %s", err)
+	}
+	if len(walked) != len(srcFiles) {
+		t.Errorf("unexpected files walked: %v", walked)
+	}
+
+	// Stats
+	statsReport, err := repo.Stats()
+	if err != nil {
+		t.Fatalf("error getting stats: %s", err)
+	}
+	if statsReport.Objects != 3 || len(statsReport.Snapshots) != 1 {
+		t.Errorf("unexpected stats: %+v", statsReport)
+	}
+
+	// Restore
+	destination := filepath.Join(testingPath, "restored")
+	restoreReport, err := repo.Restore(context.Background(), "test", destination, gkup.RestoreMode{}, opts)
+	if err != nil {
+		t.Fatalf("error restoring snapshot: %s", err)
+	}
+	if restoreReport.Files != 4 {
+		t.Errorf("unexpected restore report: %+v", restoreReport)
+	}
+	for name, content := range srcFiles {
+		data, err := ioutil.ReadFile(filepath.Join(destination, "src", filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("unexpected content of restored file %s: %s, %v", name, data, err)
+		}
+	}
+
+	// Check
+	checkReport, err := repo.Check(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("error checking repo: %s", err)
+	}
+	if checkReport.Files != 3 || len(checkReport.Failures) != 0 {
+		t.Errorf("unexpected check report: %+v", checkReport)
+	}
+	if err := ioutil.WriteFile(walkObject(snap, "src/a", t), []byte("corrupted"), 0600); err != nil {
+		t.Fatalf("error corrupting object: %s", err)
+	}
+	if checkReport, err = repo.Check(context.Background(), opts); err != nil {
+		t.Fatalf("error checking repo: %s", err)
+	}
+	if len(checkReport.Failures) != 1 {
+		t.Errorf("unexpected check report: %+v", checkReport)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func createSource(t *testing.T) {
+	for name, content := range srcFiles {
+		path := filepath.Join(srcPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating dir %s: %s", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing file %s: %s", path, err)
+		}
+	}
+}
+
+func walkObject(snap *gkup.Snapshot, filePath string, t *testing.T) string {
+	var objPath string
+	if err := snap.Walk(func(path string, f gkup.File) error {
+		if path == filePath {
+			objPath = f.ObjectPath
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("error walking snapshot: %s", err)
+	}
+	return objPath
+}
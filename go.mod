@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/Miguel-Dorta/logolang v0.5.0
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/pelletier/go-toml v1.4.0
 	github.com/pkg/errors v0.8.0
@@ -11,4 +12,5 @@ require (
 	github.com/spf13/viper v1.4.0
 	golang.org/x/crypto v0.0.0-20190611184440-5c40567a22f8
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	lukechampine.com/blake3 v1.1.7
 )
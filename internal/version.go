@@ -1,3 +1,5 @@
 package internal
 
-var Version string
+// Version is the version of gkup. It's overridden at build time with
+// -ldflags "-X github.com/Miguel-Dorta/gkup/internal.Version=<version>"
+var Version = "dev"
@@ -0,0 +1,373 @@
+// Package daemon runs backup and check jobs on schedule, one at a time per repository,
+// and keeps the status of their last runs in a status file.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/backup"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/forget"
+	"github.com/Miguel-Dorta/gkup/pkg/schedule"
+	"github.com/Miguel-Dorta/logolang"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Types of jobs
+const (
+	JobBackup = "backup" // Back up paths to a repository and apply its retention policy
+	JobCheck  = "check"  // Check the integrity of a repository
+)
+
+// Results of a job run
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Job represents a task that is run on schedule.
+type Job struct {
+	Name      string             // Name that identifies the job
+	Type      string             // JobBackup or JobCheck
+	RepoPath  string             // Path of the repository
+	Paths     []string           // Paths to back up
+	Snapshot  string             // Name of the snapshots created
+	Schedule  *schedule.Schedule // When the job is run
+	Retention forget.Policy      // Applied to the snapshots of the job after each successful backup
+	Options   *pkg.Options       // Options of the operations of the job, nil for the default ones
+}
+
+// Status represents the state of a job and the result of its last run.
+type Status struct {
+	Job          string     `json:"job"`
+	Type         string     `json:"type"`
+	Repo         string     `json:"repo"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+	LastStart    *time.Time `json:"last_start,omitempty"`
+	LastEnd      *time.Time `json:"last_end,omitempty"`
+	LastResult   string     `json:"last_result,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastSnapshot string     `json:"last_snapshot,omitempty"` // Snapshot created by the last successful backup
+}
+
+// Daemon runs jobs on schedule.
+type Daemon struct {
+	jobs       []*Job
+	statusPath string
+	log        *logolang.Logger
+
+	mutex  sync.Mutex
+	status map[string]*Status
+	busy   map[string]bool // Jobs queued or running
+	queues map[string]chan *Job
+}
+
+// New returns a Daemon that runs the jobs provided and writes their status in the path provided,
+// that can be empty for none. Its messages are written in the logger provided.
+// It returns an error if any of the jobs is not valid.
+func New(jobs []*Job, statusPath string, log *logolang.Logger) (*Daemon, error) {
+	d := &Daemon{
+		jobs:       jobs,
+		statusPath: statusPath,
+		log:        log,
+		status:     make(map[string]*Status, len(jobs)),
+		busy:       make(map[string]bool, len(jobs)),
+		queues:     make(map[string]chan *Job, len(jobs)),
+	}
+
+	for _, j := range jobs {
+		if j.Name == "" {
+			return nil, errors.New("job without name")
+		}
+		if _, exists := d.status[j.Name]; exists {
+			return nil, fmt.Errorf("duplicated job \"%s\"", j.Name)
+		}
+		switch j.Type {
+		case JobBackup:
+			if len(j.Paths) == 0 {
+				return nil, fmt.Errorf("job \"%s\" has no paths to back up", j.Name)
+			}
+		case JobCheck:
+		default:
+			return nil, fmt.Errorf("job \"%s\" has an unsupported type \"%s\"", j.Name, j.Type)
+		}
+		if j.Schedule == nil {
+			return nil, fmt.Errorf("job \"%s\" has no schedule", j.Name)
+		}
+		if j.RepoPath == "" {
+			return nil, fmt.Errorf("job \"%s\" has no repository", j.Name)
+		}
+
+		d.status[j.Name] = &Status{
+			Job:      j.Name,
+			Type:     j.Type,
+			Repo:     j.RepoPath,
+			Schedule: j.Schedule.String(),
+		}
+		d.queues[repoKey(j.RepoPath)] = nil
+	}
+	return d, nil
+}
+
+// Run runs the jobs on schedule until the context provided is cancelled. The jobs of the same repository
+// are run one at a time, and a job that is due while it's still queued or running is skipped.
+// When the context is cancelled, the jobs running are interrupted and it waits for them to end.
+func (d *Daemon) Run(ctx context.Context) error {
+	var workers, schedulers sync.WaitGroup
+	d.mutex.Lock()
+	for key := range d.queues {
+		queue := make(chan *Job, len(d.jobs))
+		d.queues[key] = queue
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range queue {
+				if ctx.Err() == nil {
+					_ = d.RunJob(ctx, j.Name)
+				}
+			}
+		}()
+	}
+	d.mutex.Unlock()
+
+	for _, j := range d.jobs {
+		schedulers.Add(1)
+		go func(j *Job) {
+			defer schedulers.Done()
+			d.schedule(ctx, j)
+		}(j)
+	}
+	d.log.Infof("Daemon started with %d jobs", len(d.jobs))
+
+	schedulers.Wait()
+	d.mutex.Lock()
+	for key, queue := range d.queues {
+		close(queue)
+		d.queues[key] = nil
+	}
+	d.mutex.Unlock()
+	workers.Wait()
+	d.log.Info("Daemon stopped")
+	return nil
+}
+
+// RunJob runs the job with the name provided immediately, updating its status.
+// It doesn't wait for the other jobs of its repository, so it must not be used while Run is running.
+func (d *Daemon) RunJob(ctx context.Context, name string) error {
+	var j *Job
+	for _, job := range d.jobs {
+		if job.Name == name {
+			j = job
+		}
+	}
+	if j == nil {
+		return fmt.Errorf("job \"%s\" not found", name)
+	}
+
+	start := time.Now()
+	d.update(j.Name, func(s *Status) {
+		s.Running = true
+		s.LastStart = &start
+	})
+	d.log.Infof("Running job %s", j.Name)
+
+	snapshot, err := runJob(ctx, j, d.log)
+
+	end := time.Now()
+	d.update(j.Name, func(s *Status) {
+		s.Running = false
+		s.LastEnd = &end
+		s.LastResult, s.LastError = ResultSuccess, ""
+		if err != nil {
+			s.LastResult, s.LastError = ResultFailure, err.Error()
+		}
+		if snapshot != "" {
+			s.LastSnapshot = snapshot
+		}
+	})
+	d.mutex.Lock()
+	d.busy[j.Name] = false
+	d.mutex.Unlock()
+
+	if err != nil {
+		d.log.Errorf("Job %s failed: %s", j.Name, err)
+		return err
+	}
+	d.log.Infof("Job %s finished in %s", j.Name, end.Sub(start).Round(time.Second))
+	return nil
+}
+
+// Status returns the status of all the jobs, sorted by name.
+func (d *Daemon) Status() []Status {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.statusList()
+}
+
+// schedule queues the job provided every time it's due, until the context provided is cancelled.
+func (d *Daemon) schedule(ctx context.Context, j *Job) {
+	for {
+		next := j.Schedule.Next(time.Now())
+		if next.IsZero() {
+			d.log.Errorf("Job %s will never run, its schedule has no dates", j.Name)
+			return
+		}
+		d.update(j.Name, func(s *Status) {
+			s.NextRun = &next
+		})
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		d.mutex.Lock()
+		if d.busy[j.Name] {
+			d.mutex.Unlock()
+			d.log.Errorf("Job %s is still queued or running, skipping", j.Name)
+			continue
+		}
+		d.busy[j.Name] = true
+		d.queues[repoKey(j.RepoPath)] <- j
+		d.mutex.Unlock()
+	}
+}
+
+// update updates the status of the job with the name provided with the function provided,
+// and writes the status file.
+func (d *Daemon) update(name string, fn func(s *Status)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	fn(d.status[name])
+	if d.statusPath == "" {
+		return
+	}
+	if err := writeStatus(d.statusPath, d.statusList()); err != nil {
+		d.log.Errorf("Error writing status file: %s", err)
+	}
+}
+
+// statusList returns a copy of the status of all the jobs, sorted by name. The mutex must be locked.
+func (d *Daemon) statusList() []Status {
+	list := make([]Status, 0, len(d.status))
+	for _, s := range d.status {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Job < list[j].Job
+	})
+	return list
+}
+
+// runJob runs the job provided, returning the ID of the snapshot created, if any.
+func runJob(ctx context.Context, j *Job, log *logolang.Logger) (string, error) {
+	opts := j.Options.Normalize()
+	opts.Log = log
+	opts.Progress = nil
+
+	switch j.Type {
+	case JobBackup:
+		report, err := backup.Run(ctx, j.RepoPath, j.Snapshot, j.Paths, opts)
+		if err != nil {
+			if report != nil {
+				return report.Snapshot, err
+			}
+			return "", err
+		}
+		if !j.Retention.IsZero() {
+			forgetReport, err := forget.Run(ctx, j.RepoPath, j.Snapshot, j.Retention, opts)
+			if err != nil {
+				return report.Snapshot, fmt.Errorf("error applying retention policy: %w", err)
+			}
+			log.Infof("Job %s removed %d snapshots and %d objects (%d bytes)",
+				j.Name, len(forgetReport.Removed), forgetReport.Objects, forgetReport.Bytes)
+		}
+		return report.Snapshot, nil
+	case JobCheck:
+		report, err := check.FindFailures(ctx, j.RepoPath, opts)
+		if err != nil {
+			return "", err
+		}
+		for _, f := range report.Failures {
+			log.Errorf("Job %s: %s: %s", j.Name, f.Path, f.Err)
+		}
+		if len(report.Failures) != 0 {
+			return "", fmt.Errorf("%d of %d files failed the check: %w", len(report.Failures), report.Files, check.ErrIntegrity)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported job type \"%s\"", j.Type)
+}
+
+// writeStatus writes the status provided as JSON in the path provided, replacing it atomically.
+func writeStatus(path string, list []Status) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing status: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return &os.PathError{
+			Op:   "create status file",
+			Path: path,
+			Err:  err,
+		}
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return &os.PathError{
+			Op:   "write status file",
+			Path: tmp.Name(),
+			Err:  err,
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return &os.PathError{
+			Op:   "close status file",
+			Path: tmp.Name(),
+			Err:  err,
+		}
+	}
+	if err := os.Chmod(tmp.Name(), pkg.DefaultFilePerm); err != nil {
+		_ = os.Remove(tmp.Name())
+		return &os.PathError{
+			Op:   "chmod status file",
+			Path: tmp.Name(),
+			Err:  err,
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return &os.PathError{
+			Op:   "replace status file",
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// repoKey returns the key that identifies the repository of the path provided.
+func repoKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
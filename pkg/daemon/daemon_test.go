@@ -0,0 +1,120 @@
+package daemon_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/daemon"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/forget"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/schedule"
+	"github.com/Miguel-Dorta/logolang"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_TestDaemon")
+	repoPath    = filepath.Join(testingPath, "repo")
+	srcPath     = filepath.Join(testingPath, "src")
+	statusPath  = filepath.Join(testingPath, "status.json")
+)
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestDaemon_RunJob(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	if err := os.MkdirAll(srcPath, 0755); err != nil {
+		t.Fatalf("error creating source: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(srcPath, "a"), []byte("content"), 0644); err != nil {
+		t.Fatalf("error writing source: %s", err)
+	}
+	old := snapshots.NewID("home", time.Now().Add(-time.Hour))
+	if err := snapshots.Write(old.Path(repoPath), &snapshots.Snapshot{}); err != nil {
+		t.Fatalf("error writing old snapshot: %s", err)
+	}
+
+	s, err := schedule.Parse("@daily")
+	if err != nil {
+		t.Fatalf("error parsing schedule: %s", err)
+	}
+	opts := &pkg.Options{BufferSize: 512, NumberOfThreads: 2}
+	d, err := daemon.New([]*daemon.Job{
+		{Name: "home", Type: daemon.JobBackup, RepoPath: repoPath, Paths: []string{srcPath}, Snapshot: "home",
+			Schedule: s, Retention: forget.Policy{KeepLast: 1}, Options: opts},
+		{Name: "verify", Type: daemon.JobCheck, RepoPath: repoPath, Schedule: s, Options: opts},
+	}, statusPath, newLogger())
+	if err != nil {
+		t.Fatalf("error creating daemon: %s", err)
+	}
+
+	if err := d.RunJob(context.Background(), "home"); err != nil {
+		t.Fatalf("error running backup job: %s", err)
+	}
+	ids, err := snapshots.ListByName(repoPath, "home")
+	if err != nil || len(ids) != 1 || ids[0] == old {
+		t.Errorf("unexpected snapshots after backup job with retention: %v, %v", ids, err)
+	}
+	if err := d.RunJob(context.Background(), "verify"); err != nil {
+		t.Errorf("error running check job: %s", err)
+	}
+	if err := d.RunJob(context.Background(), "missing"); err == nil {
+		t.Error("not error running a job that doesn't exist")
+	}
+
+	// Status file
+	data, err := ioutil.ReadFile(statusPath)
+	if err != nil {
+		t.Fatalf("error reading status file: %s", err)
+	}
+	var status []daemon.Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatalf("error parsing status file %s: %s", data, err)
+	}
+	if len(status) != 2 || status[0].Job != "home" || status[1].Job != "verify" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if status[0].LastResult != daemon.ResultSuccess || status[0].LastSnapshot != ids[0].String() || status[0].Running {
+		t.Errorf("unexpected status of backup job: %+v", status[0])
+	}
+	if status[1].LastResult != daemon.ResultSuccess || status[1].LastEnd == nil {
+		t.Errorf("unexpected status of check job: %+v", status[1])
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	s, _ := schedule.Parse("@daily")
+	for _, jobs := range [][]*daemon.Job{
+		{{Type: daemon.JobCheck, RepoPath: repoPath, Schedule: s}},
+		{{Name: "a", Type: "prune", RepoPath: repoPath, Schedule: s}},
+		{{Name: "a", Type: daemon.JobBackup, RepoPath: repoPath, Schedule: s}},
+		{{Name: "a", Type: daemon.JobCheck, RepoPath: repoPath}},
+		{{Name: "a", Type: daemon.JobCheck, Schedule: s}},
+		{{Name: "a", Type: daemon.JobCheck, RepoPath: repoPath, Schedule: s}, {Name: "a", Type: daemon.JobCheck, RepoPath: repoPath, Schedule: s}},
+	} {
+		if _, err := daemon.New(jobs, "", newLogger()); err == nil {
+			t.Errorf("not error creating daemon with invalid jobs: %+v", jobs[0])
+		}
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func newLogger() *logolang.Logger {
+	l := logolang.NewLogger()
+	l.Level = logolang.LevelNoLog
+	return l
+}
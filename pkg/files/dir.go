@@ -4,19 +4,34 @@ import (
 	"fmt"
 	"github.com/Miguel-Dorta/gkup/pkg"
 	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"os"
 	"path/filepath"
+	"time"
 )
 
 // Dir represents an abstraction of a directory
 type Dir struct {
-	Name  string  `json:"name"`
-	Dirs  []Dir   `json:"dirs"`
-	Files []*File `json:"files"`
+	Name    string      `json:"name"`
+	Mode    os.FileMode `json:"mode,omitempty"`     // Permissions, 0 if they're unknown
+	ModTime *time.Time  `json:"mod_time,omitempty"` // Modification time, nil if it's unknown
+	Dirs    []Dir       `json:"dirs"`
+	Files   []*File     `json:"files"`
 }
 
 // NewDir returns a Dir object that represents the complete structure from the path provided
-// and a slice of File objects containing all the files from that structure
-func NewDir(path string) (Dir, []*File, error) {
+// and a slice of File objects containing all the files from that structure.
+// The files are filtered and the errors handled according to the options provided.
+func NewDir(path string, opts *pkg.Options) (Dir, []*File, error) {
+	return newDir(path, opts.Normalize())
+}
+
+// newDir is like NewDir, but the options provided must be already normalized.
+func newDir(path string, opts *pkg.Options) (Dir, []*File, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Dir{}, nil, fmt.Errorf("cannot get information of \"%s\": %s", path, err.Error())
+	}
+
 	// Check if it's a directory
 	children, err := utils.ListDir(path)
 	if err != nil {
@@ -24,27 +39,39 @@ func NewDir(path string) (Dir, []*File, error) {
 	}
 
 	var fileList []*File
+	modTime := stat.ModTime()
 	d := Dir{
-		Name:  filepath.Base(path),
-		Files: make([]*File, 0, pkg.SliceSmallCapacity),
-		Dirs:  make([]Dir, 0, pkg.SliceSmallCapacity),
+		Name:    filepath.Base(path),
+		Mode:    stat.Mode().Perm(),
+		ModTime: &modTime,
+		Files:   make([]*File, 0, pkg.SliceSmallCapacity),
+		Dirs:    make([]Dir, 0, pkg.SliceSmallCapacity),
 	}
 
 	for _, child := range children {
 		childPath := filepath.Join(path, child.Name())
 
 		// Omit if hidden
-		if pkg.OmitHidden && utils.IsHidden(child.Name()) {
-			pkg.Log.Debugf("omitting hidden file %s", childPath)
+		if opts.OmitHidden && utils.IsHidden(child.Name()) {
+			opts.Log.Debugf("omitting hidden file %s", childPath)
+			opts.OnSkip(childPath, "hidden")
+			continue
+		}
+
+		// Omit if excluded
+		if utils.IsExcluded(child.Name(), opts.Exclude) {
+			opts.Log.Debugf("omitting excluded file %s", childPath)
+			opts.OnSkip(childPath, "excluded")
 			continue
 		}
 
 		if child.Mode().IsDir() { // If child is a directory, list it, and add it to this directory list of directories, and its files to the filelist.
-			pkg.Log.Debugf("Listing directory %s", childPath)
-			subChild, childFiles, err := NewDir(childPath)
+			opts.Log.Debugf("Listing directory %s", childPath)
+			subChild, childFiles, err := newDir(childPath, opts)
 			if err != nil {
-				if pkg.OmitErrors {
-					pkg.Log.Error(err.Error())
+				if opts.OmitErrors {
+					opts.Log.Error(err.Error())
+					opts.OnSkip(childPath, err.Error())
 					continue
 				} else {
 					return Dir{}, nil, err
@@ -54,11 +81,12 @@ func NewDir(path string) (Dir, []*File, error) {
 			fileList = append(fileList, childFiles...)
 
 		} else if child.Mode().IsRegular() { // If child is a file, add it to this directory list of files
-			pkg.Log.Debugf("Listing file %s", childPath)
+			opts.Log.Debugf("Listing file %s", childPath)
 			subChild, err := NewFile(childPath)
 			if err != nil {
-				if pkg.OmitErrors {
-					pkg.Log.Error(err.Error())
+				if opts.OmitErrors {
+					opts.Log.Error(err.Error())
+					opts.OnSkip(childPath, err.Error())
 					continue
 				} else {
 					return Dir{}, nil, err
@@ -67,7 +95,8 @@ func NewDir(path string) (Dir, []*File, error) {
 			d.Files = append(d.Files, subChild)
 
 		} else { // If child is neither a directory nor a file, omit it
-			pkg.Log.Debugf("omitting unsupported file %s", childPath)
+			opts.Log.Debugf("omitting unsupported file %s", childPath)
+			opts.OnSkip(childPath, "unsupported file type")
 		}
 	}
 
@@ -2,6 +2,7 @@ package files_test
 
 import (
 	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/pkg"
 	"github.com/Miguel-Dorta/gkup/pkg/files"
 	"sort"
 	"strings"
@@ -9,7 +10,7 @@ import (
 )
 
 func TestNewDir(t *testing.T) {
-	d, fileList, err := files.NewDir("../../test")
+	d, fileList, err := files.NewDir("../../test", nil)
 	if err != nil {
 		t.Fatalf("error listing working directory: %s", err)
 	}
@@ -20,6 +21,8 @@ func TestNewDir(t *testing.T) {
 		return strings.ToLower(fileList[i].RealPath) < strings.ToLower(fileList[j].RealPath)
 	})
 
+	// Modes and modification times depend on the checkout
+	d = clearInfo(d, t)
 	data, err := json.Marshal(d)
 	if err != nil {
 		t.Fatalf("error converting dir to JSON: %s", err)
@@ -32,6 +35,50 @@ func TestNewDir(t *testing.T) {
 	}
 }
 
+func TestNewDir_Exclude(t *testing.T) {
+	opts := pkg.NewOptions()
+	opts.Exclude = []string{"aYVxBryiOoTL", "*Z*"}
+
+	d, fileList, err := files.NewDir("../../test", opts)
+	if err != nil {
+		t.Fatalf("error listing working directory: %s", err)
+	}
+
+	for _, dir := range d.Dirs {
+		if dir.Name == "aYVxBryiOoTL" {
+			t.Errorf("excluded directory %s found", dir.Name)
+		}
+	}
+	for _, f := range fileList {
+		if strings.Contains(f.Name, "Z") || strings.Contains(f.RealPath, "aYVxBryiOoTL") {
+			t.Errorf("excluded file %s found", f.RealPath)
+		}
+	}
+	if len(fileList) != 20 {
+		t.Errorf("unexpected number of files\n-> Expected: 20\n-> Found: %d", len(fileList))
+	}
+}
+
+// clearInfo checks that the permissions and the modification time of the files.Dir provided and its children
+// were recorded, and returns it without them.
+func clearInfo(d files.Dir, t *testing.T) files.Dir {
+	if d.Mode == 0 || d.ModTime == nil {
+		t.Errorf("permissions or modification time not recorded in directory %s", d.Name)
+	}
+	d.Mode, d.ModTime = 0, nil
+
+	for _, f := range d.Files {
+		if f.Mode == 0 || f.ModTime == nil {
+			t.Errorf("permissions or modification time not recorded in file %s", f.RealPath)
+		}
+		f.Mode, f.ModTime = 0, nil
+	}
+	for i := range d.Dirs {
+		d.Dirs[i] = clearInfo(d.Dirs[i], t)
+	}
+	return d
+}
+
 func sortDir(d files.Dir) files.Dir {
 	sort.Slice(d.Dirs, func(i, j int) bool {
 		return strings.ToLower(d.Dirs[i].Name) < strings.ToLower(d.Dirs[j].Name)
@@ -6,14 +6,19 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // File represents an abstraction of a file
 type File struct {
-	Name     string `json:"name"`
-	Size     int64  `json:"size"`
-	Hash     []byte `json:"hash"`
-	RealPath string `json:"-"`
+	Name      string      `json:"name"`
+	Size      int64       `json:"size"`
+	Hash      []byte      `json:"hash"`
+	Collision int         `json:"collision,omitempty"` // Index of the object among the ones with the same hash and size
+	Mode      os.FileMode `json:"mode,omitempty"`      // Permissions, 0 if they're unknown
+	ModTime   *time.Time  `json:"mod_time,omitempty"`  // Modification time, nil if it's unknown
+	RealPath  string      `json:"-"`
 }
 
 // NewFile gets a File object from the path provided without hashing it
@@ -23,17 +28,21 @@ func NewFile(path string) (*File, error) {
 		return nil, fmt.Errorf("cannot get information of \"%s\": %s", path, err.Error())
 	}
 
+	modTime := stat.ModTime()
 	return &File{
 		Name:     stat.Name(),
 		Size:     stat.Size(),
 		Hash:     nil,
+		Mode:     stat.Mode().Perm(),
+		ModTime:  &modTime,
 		RealPath: path,
 	}, nil
 }
 
 // GetFileFromName gets a File object from a string formed by the file's hash and its size.
 // The hash is encoded in hexadecimal notation. The size is written in base 10.
-// Both are separated by the character '-'
+// Both are separated by the character '-'. If the object collided with another one with the same hash and size,
+// it will end with another '-' followed by the index of the collision.
 func GetFileFromName(fileName string) (*File, error) {
 	var err error
 	f := File{
@@ -50,7 +59,15 @@ func GetFileFromName(fileName string) (*File, error) {
 			return nil, fmt.Errorf("cannot decode hash: %s", err.Error())
 		}
 
-		if f.Size, err = strconv.ParseInt(fileName[i+1:], 10, 64); err != nil {
+		sizeStr := fileName[i+1:]
+		if j := strings.IndexByte(sizeStr, '-'); j >= 0 {
+			if f.Collision, err = strconv.Atoi(sizeStr[j+1:]); err != nil || f.Collision < 1 {
+				return nil, errors.New("invalid collision index")
+			}
+			sizeStr = sizeStr[:j]
+		}
+
+		if f.Size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil {
 			return nil, fmt.Errorf("cannot parse size: %s", err.Error())
 		}
 		break
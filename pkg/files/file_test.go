@@ -27,6 +27,15 @@ func TestGetFileFromName(t *testing.T) {
 				Hash: []byte{189, 251, 17, 241, 158, 81, 243, 96, 227, 145, 178, 74, 18, 39, 78, 179},
 			},
 		},
+		{
+			"bdfb11f19e51f360e391b24a12274eb3-2135-2",
+			files.File{
+				Name: "bdfb11f19e51f360e391b24a12274eb3-2135-2",
+				Size: 2135,
+				Hash: []byte{189, 251, 17, 241, 158, 81, 243, 96, 227, 145, 178, 74, 18, 39, 78, 179},
+				Collision: 2,
+			},
+		},
 		{
 			"6a04e805e2080321ace61f0403e94b7ef75c06c4394a94a1f528d242387ef3f487baa2a9b891629b986cc95a9332bad681d8adfbb20352dd8a89da61a2fce974-531",
 			files.File{
@@ -42,6 +51,8 @@ func TestGetFileFromName(t *testing.T) {
 		"6878758d2aed4646ee85cb88e7c60817edb51fcaec25f1a9c4939deadb93fbax-1658696a4",
 		"6878758d2aed4646ee85cb88e7c60817edb51fcaec25f1a9c493-deadb93fbax-165869644",
 		"6878758d2aed4646ee85cb88e7c60817edb51fcaec25f19c4939deadb93fbax-165869-644",
+		"bdfb11f19e51f360e391b24a12274eb3-2135-0",
+		"bdfb11f19e51f360e391b24a12274eb3-2135-",
 		"Generic-filename.txt",
 		"",
 	}
@@ -64,6 +75,10 @@ func TestGetFileFromName(t *testing.T) {
 		if !bytes.Equal(actualResult.Hash, valid.expectedResult.Hash) {
 			t.Errorf("expected hash is not what was found.\n-> Expected: %+v\n-> Found: %+v", valid.expectedResult.Hash, actualResult.Hash)
 		}
+
+		if actualResult.Collision != valid.expectedResult.Collision {
+			t.Errorf("expected collision is not what was found.\n-> Expected: %d\n-> Found: %d", valid.expectedResult.Collision, actualResult.Collision)
+		}
 	}
 
 	for _, invalid := range invalidFileNames {
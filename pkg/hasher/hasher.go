@@ -2,50 +2,37 @@ package hasher
 
 import (
 	"bytes"
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
 	"fmt"
 	"github.com/Miguel-Dorta/gkup/pkg"
 	"github.com/Miguel-Dorta/gkup/pkg/files"
-	"golang.org/x/crypto/sha3"
 	"hash"
 	"io"
 	"os"
-	"strings"
 )
 
 // Hasher is a type for making hashing operations
 type Hasher struct {
 	hash hash.Hash
 	buf  []byte
+	opts *pkg.Options
 }
 
-// New creates a new Hasher object
-func New(algorithm string) (*Hasher, error) {
-	var h hash.Hash
-
-	switch strings.ToLower(algorithm) {
-	case "sha256":
-		h = sha256.New() // Most frequent case the first
-	case "md5":
-		h = md5.New()
-	case "sha1":
-		h = sha1.New()
-	case "sha512":
-		h = sha512.New()
-	case "sha3-256":
-		h = sha3.New256()
-	case "sha3-512":
-		h = sha3.New512()
-	default:
-		return nil, fmt.Errorf("hash algorithm %s is not supported", algorithm)
+// New creates a new Hasher object that will use the options provided
+func New(algorithm string, opts *pkg.Options) (*Hasher, error) {
+	return newHasher(algorithm, opts.Normalize())
+}
+
+// newHasher is like New, but the options provided must be already normalized.
+func newHasher(algorithm string, opts *pkg.Options) (*Hasher, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Hasher{
 		hash: h,
-		buf:  make([]byte, pkg.BufferSize),
+		buf:  make([]byte, opts.BufferSize),
+		opts: opts,
 	}, nil
 }
 
@@ -62,7 +49,7 @@ func (h *Hasher) HashFile(f *files.File) error {
 	defer file.Close()
 
 	h.hash.Reset()
-	pkg.Log.Debugf("Hashing file %s", f.RealPath)
+	h.opts.Log.Debugf("Hashing file %s", f.RealPath)
 	if _, err := io.CopyBuffer(h.hash, file, h.buf); err != nil {
 		return fmt.Errorf("error hashing file \"%s\": %s", f.RealPath, err.Error())
 	}
@@ -80,7 +67,7 @@ func (h *Hasher) HashPath(path string) ([]byte, error) {
 	defer f.Close()
 
 	h.hash.Reset()
-	pkg.Log.Debugf("Hashing path %s", path)
+	h.opts.Log.Debugf("Hashing path %s", path)
 	if _, err := io.CopyBuffer(h.hash, f, h.buf); err != nil {
 		return nil, fmt.Errorf("error hashing file \"%s\": %s", path, err.Error())
 	}
@@ -94,7 +81,7 @@ func (h *Hasher) GetFile(path string) (*files.File, error) {
 		return nil, fmt.Errorf("cannot get information of \"%s\": %s", path, err.Error())
 	}
 
-	pkg.Log.Debugf("Hashing path %s and returning file", path)
+	h.opts.Log.Debugf("Hashing path %s and returning file", path)
 	if f.Hash, err = h.HashPath(path); err != nil {
 		return nil, err
 	}
@@ -172,7 +172,7 @@ func TestNew(t *testing.T) {
 
 	// Check valid algorithms
 	for _, a := range validAlgorithms {
-		_, err := hasher.New(a)
+		_, err := hasher.New(a, nil)
 		if err != nil {
 			t.Errorf("Valid hash algorithm not accepted: %s", a)
 		}
@@ -180,7 +180,7 @@ func TestNew(t *testing.T) {
 
 	// Check invalid algorithms
 	for _, a := range invalidAlgorithms {
-		_, err := hasher.New(a)
+		_, err := hasher.New(a, nil)
 		if err == nil {
 			t.Errorf("Invalid algorithms accepted: %s", a)
 		}
@@ -189,7 +189,7 @@ func TestNew(t *testing.T) {
 
 func Test_HashPath(t *testing.T) {
 	// Create hasher
-	h, err := hasher.New("sha256")
+	h, err := hasher.New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 not accepted as hash. TestNew() will probably fail too")
 	}
@@ -212,7 +212,7 @@ func Test_HashPath(t *testing.T) {
 
 func Test_GetFile(t *testing.T) {
 	// Create hasher
-	h, err := hasher.New("sha256")
+	h, err := hasher.New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 not accepted as hash. TestNew() will probably fail too")
 	}
@@ -243,7 +243,7 @@ func Test_GetFile(t *testing.T) {
 
 func Test_HashFile(t *testing.T) {
 	// Create hasher
-	h, err := hasher.New("sha256")
+	h, err := hasher.New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 not accepted as hash. TestNew() will probably fail too")
 	}
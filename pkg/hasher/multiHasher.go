@@ -1,8 +1,10 @@
 package hasher
 
 import (
+	"context"
 	"github.com/Miguel-Dorta/gkup/pkg"
 	"github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
 	"golang.org/x/sync/errgroup"
 	"sync"
@@ -13,13 +15,14 @@ type MultiHasher struct {
 	workers []*Hasher
 }
 
-// NewMultiHasher creates a new MultiHasher object
-func NewMultiHasher(algorithm string) (*MultiHasher, error) {
+// NewMultiHasher creates a new MultiHasher object that will use the options provided
+func NewMultiHasher(algorithm string, opts *pkg.Options) (*MultiHasher, error) {
 	var err error
 
-	workers := make([]*Hasher, pkg.NumberOfThreads)
+	opts = opts.Normalize()
+	workers := make([]*Hasher, opts.NumberOfThreads)
 	for i := range workers {
-		workers[i], err = New(algorithm)
+		workers[i], err = newHasher(algorithm, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -30,29 +33,31 @@ func NewMultiHasher(algorithm string) (*MultiHasher, error) {
 // CheckFiles checks concurrently whether the files listed in the path slice provided match with the info contained in their names.
 // That means that they follow the specification from files.GetFileFromName() and their information is correct.
 // This process is aimed to detect file corruption or filename defects.
-func (mh *MultiHasher) CheckFiles(paths []string) bool {
+// It stops when the context provided is cancelled.
+func (mh *MultiHasher) CheckFiles(ctx context.Context, paths []string) bool {
 	var wg sync.WaitGroup
 	var errsFound fuse
 	pathsSafe := threadSafe.NewStringList(paths)
 
 	for _, w := range mh.workers {
 		wg.Add(1)
-		go w.fileChecker(pathsSafe, &errsFound, &wg)
+		go w.fileChecker(ctx, pathsSafe, &errsFound, &wg)
 	}
 
 	wg.Wait()
 	return bool(errsFound)
 }
 
-// GetFiles creates concurrently a list of files.File from the path list provided
-func (mh *MultiHasher) GetFiles(paths []string) ([]*files.File, error) {
+// GetFiles creates concurrently a list of files.File from the path list provided until the context provided is cancelled
+func (mh *MultiHasher) GetFiles(ctx context.Context, paths []string) ([]*files.File, error) {
 	var eg errgroup.Group
 	pathsSafe := threadSafe.NewStringList(paths)
 	filesSafe := threadSafe.NewFileList(make([]*files.File, 0, len(paths)))
 
 	for _, w := range mh.workers {
+		w := w
 		eg.Go(func() error {
-			return w.fileGetter(pathsSafe, filesSafe)
+			return w.fileGetter(ctx, pathsSafe, filesSafe)
 		})
 	}
 
@@ -62,14 +67,16 @@ func (mh *MultiHasher) GetFiles(paths []string) ([]*files.File, error) {
 	return filesSafe.GetList(), nil
 }
 
-// HashFiles gets concurrently the hash from the list of files.File provided
-func (mh *MultiHasher) HashFiles(files []*files.File) error {
+// HashFiles gets concurrently the hash from the list of files.File provided, reporting its progress to the tracker provided,
+// until the context provided is cancelled
+func (mh *MultiHasher) HashFiles(ctx context.Context, files []*files.File, tracker *progress.Tracker) error {
 	var eg errgroup.Group
 	filesSafe := threadSafe.NewFileList(files)
 
 	for _, w := range mh.workers {
+		w := w
 		eg.Go(func() error {
-			return w.fileHasher(filesSafe)
+			return w.fileHasher(ctx, filesSafe, tracker)
 		})
 	}
 
@@ -0,0 +1,128 @@
+package hasher
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"lukechampine.com/blake3"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Algorithm represents a hash algorithm that can be used in a repository.
+type Algorithm struct {
+	// Name of the algorithm, as it's written in the repository settings. It's case-insensitive.
+	Name string
+
+	// Function that returns a new hash.Hash of the algorithm.
+	New func() hash.Hash
+
+	// Size of the digest, in bytes.
+	Size int
+
+	// Whether the algorithm is considered secure against collisions.
+	Secure bool
+}
+
+var (
+	algorithms      = make(map[string]Algorithm, 10)
+	algorithmsMutex sync.RWMutex
+)
+
+func init() {
+	for _, a := range []Algorithm{
+		{Name: "md5", New: md5.New, Size: md5.Size, Secure: false},
+		{Name: "sha1", New: sha1.New, Size: sha1.Size, Secure: false},
+		{Name: "sha256", New: sha256.New, Size: sha256.Size, Secure: true},
+		{Name: "sha512", New: sha512.New, Size: sha512.Size, Secure: true},
+		{Name: "sha3-256", New: sha3.New256, Size: 32, Secure: true},
+		{Name: "sha3-512", New: sha3.New512, Size: 64, Secure: true},
+		{Name: "blake2b-256", New: newBlake2b256, Size: blake2b.Size256, Secure: true},
+		{Name: "blake2b-512", New: newBlake2b512, Size: blake2b.Size, Secure: true},
+		{Name: "blake3", New: newBlake3, Size: 32, Secure: true},
+	} {
+		if err := Register(a); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds the algorithm provided to the list of supported algorithms.
+// It returns an error if the algorithm is incomplete, if its size doesn't match the size of the digests
+// of its hashes, or if an algorithm with the same name is already registered.
+func Register(a Algorithm) error {
+	if a.Name == "" || a.New == nil || a.Size <= 0 {
+		return errors.New("incomplete hash algorithm")
+	}
+	name := strings.ToLower(a.Name)
+	if size := len(a.New().Sum(nil)); size != a.Size {
+		return fmt.Errorf("hash algorithm %s has a size of %d bytes, but its digests have %d", name, a.Size, size)
+	}
+
+	algorithmsMutex.Lock()
+	defer algorithmsMutex.Unlock()
+
+	if _, exists := algorithms[name]; exists {
+		return fmt.Errorf("hash algorithm %s is already registered", name)
+	}
+	a.Name = name
+	algorithms[name] = a
+	return nil
+}
+
+// GetAlgorithm returns the registered algorithm with the name provided.
+func GetAlgorithm(name string) (Algorithm, error) {
+	algorithmsMutex.RLock()
+	defer algorithmsMutex.RUnlock()
+
+	a, ok := algorithms[strings.ToLower(name)]
+	if !ok {
+		return Algorithm{}, fmt.Errorf("hash algorithm %s is not supported", name)
+	}
+	return a, nil
+}
+
+// Algorithms returns all the registered algorithms sorted by name.
+func Algorithms() []Algorithm {
+	algorithmsMutex.RLock()
+	defer algorithmsMutex.RUnlock()
+
+	list := make([]Algorithm, 0, len(algorithms))
+	for _, a := range algorithms {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// NewHash returns a new hash.Hash of the algorithm provided
+func NewHash(algorithm string) (hash.Hash, error) {
+	a, err := GetAlgorithm(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return a.New(), nil
+}
+
+func newBlake2b256() hash.Hash {
+	h, _ := blake2b.New256(nil) // It only fails with invalid keys
+	return h
+}
+
+func newBlake2b512() hash.Hash {
+	h, _ := blake2b.New512(nil) // It only fails with invalid keys
+	return h
+}
+
+func newBlake3() hash.Hash {
+	return blake3.New(32, nil)
+}
@@ -0,0 +1,81 @@
+package hasher_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"hash"
+	"testing"
+)
+
+func TestNewHash(t *testing.T) {
+	digests := map[string]string{
+		"MD5":         "900150983cd24fb0d6963f7d28e17f72",
+		"sha1":        "a9993e364706816aba3e25717850c26c9cd0d89d",
+		"sha256":      "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"sha3-256":    "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		"blake2b-256": "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319",
+		"BLAKE2b-512": "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		"blake3":      "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+	}
+
+	for algorithm, expected := range digests {
+		h, err := hasher.NewHash(algorithm)
+		if err != nil {
+			t.Errorf("error getting hash %s: %s", algorithm, err)
+			continue
+		}
+		_, _ = h.Write([]byte("abc"))
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+			t.Errorf("unexpected digest of %s\n-> Expected: %s\n-> Found: %s", algorithm, expected, actual)
+		}
+
+		a, err := hasher.GetAlgorithm(algorithm)
+		if err != nil {
+			t.Errorf("error getting algorithm %s: %s", algorithm, err)
+			continue
+		}
+		if a.Size != len(expected)/2 || a.Size != h.Size() {
+			t.Errorf("unexpected size of %s: %d", algorithm, a.Size)
+		}
+	}
+
+	if _, err := hasher.NewHash("crc32"); err == nil {
+		t.Error("not error getting unknown hash")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := hasher.Register(hasher.Algorithm{Name: "SHA256", New: md5.New, Size: md5.Size}); err == nil {
+		t.Error("not error registering an already registered algorithm")
+	}
+	if err := hasher.Register(hasher.Algorithm{Name: "incomplete", Size: 16}); err == nil {
+		t.Error("not error registering an incomplete algorithm")
+	}
+	if err := hasher.Register(hasher.Algorithm{Name: "wrong-md5", New: md5.New, Size: 32}); err == nil {
+		t.Error("not error registering an algorithm with a wrong size")
+	}
+	if _, err := hasher.GetAlgorithm("wrong-md5"); err == nil {
+		t.Error("algorithm with a wrong size registered")
+	}
+
+	if err := hasher.Register(hasher.Algorithm{Name: "My-MD5", New: md5.New, Size: md5.Size}); err != nil {
+		t.Fatalf("error registering custom algorithm: %s", err)
+	}
+	a, err := hasher.GetAlgorithm("my-md5")
+	if err != nil {
+		t.Fatalf("error getting custom algorithm: %s", err)
+	}
+	if a.Name != "my-md5" || a.Secure {
+		t.Errorf("unexpected custom algorithm: %+v", a)
+	}
+	var _ hash.Hash = a.New()
+
+	found := false
+	for _, a := range hasher.Algorithms() {
+		found = found || a.Name == "my-md5"
+	}
+	if !found {
+		t.Error("custom algorithm not found in the list of algorithms")
+	}
+}
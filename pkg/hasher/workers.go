@@ -1,7 +1,8 @@
 package hasher
 
 import (
-	"github.com/Miguel-Dorta/gkup/pkg"
+	"context"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
 	"sync"
 )
@@ -9,28 +10,33 @@ import (
 // fileChecker is a worker that reads paths and checks whether the files listed in the path slice provided match with the info contained in their names.
 // That means that they follow the specification from files.GetFileFromName() and their information is correct.
 // This process is aimed to detect file corruption or filename defects.
-// It returns the number of errors found
-func (h *Hasher) fileChecker(in *threadSafe.StringList, errsFound *fuse, wg *sync.WaitGroup) {
-	for {
+// It stops when the context provided is cancelled.
+func (h *Hasher) fileChecker(ctx context.Context, in *threadSafe.StringList, errsFound *fuse, wg *sync.WaitGroup) {
+	for ctx.Err() == nil {
 		path := in.Next()
 		if path == nil {
 			break
 		}
 
-		pkg.Log.Debugf("Checking integrity of %s", *path)
+		h.opts.Log.Debugf("Checking integrity of %s", *path)
 		if err := h.CheckFileIntegrity(*path); err != nil {
-			pkg.Log.Errorf("Error checking integrity of file \"%s\": %s", *path, err)
+			h.opts.Log.Errorf("Error checking integrity of file \"%s\": %s", *path, err)
 			errsFound.trigger()
 			continue
 		}
-		pkg.Log.Debugf("File %s is correct", *path)
+		h.opts.Log.Debugf("File %s is correct", *path)
 	}
 	wg.Done()
 }
 
 // fileGetter is a worker that reads paths, gets its files.File, and write those last ones in a list.
-func (h *Hasher) fileGetter(in *threadSafe.StringList, out *threadSafe.FileList) error {
+// It stops and returns the error of the context provided when it's cancelled.
+func (h *Hasher) fileGetter(ctx context.Context, in *threadSafe.StringList, out *threadSafe.FileList) error {
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		path := in.Next()
 		if path == nil {
 			break
@@ -38,8 +44,8 @@ func (h *Hasher) fileGetter(in *threadSafe.StringList, out *threadSafe.FileList)
 
 		f, err := h.GetFile(*path)
 		if err != nil {
-			if pkg.OmitErrors {
-				pkg.Log.Errorf("Error hashing file \"%s\": %s\n", *path, err.Error())
+			if h.opts.OmitErrors {
+				h.opts.Log.Errorf("Error hashing file \"%s\": %s\n", *path, err.Error())
 				continue
 			} else {
 				return err
@@ -51,17 +57,25 @@ func (h *Hasher) fileGetter(in *threadSafe.StringList, out *threadSafe.FileList)
 	return nil
 }
 
-// fileHasher is a worker that gets and assigns the hash from the files.File provided
-func (h *Hasher) fileHasher(list *threadSafe.FileList) error {
+// fileHasher is a worker that gets and assigns the hash from the files.File provided.
+// It stops and returns the error of the context provided when it's cancelled.
+func (h *Hasher) fileHasher(ctx context.Context, list *threadSafe.FileList, tracker *progress.Tracker) error {
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		f := list.Next()
 		if f == nil {
 			break
 		}
 
-		if err := h.HashFile(f); err != nil {
-			if pkg.OmitErrors {
-				pkg.Log.Error(err.Error())
+		tracker.SetCurrentFile(f.RealPath)
+		err := h.HashFile(f)
+		tracker.Add(1, f.Size)
+		if err != nil {
+			if h.opts.OmitErrors {
+				h.opts.Log.Error(err.Error())
 				continue
 			} else {
 				return err
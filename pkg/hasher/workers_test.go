@@ -1,8 +1,10 @@
 package hasher
 
 import (
+	"context"
 	"encoding/hex"
 	"github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
 	"io/ioutil"
 	"os"
@@ -166,7 +168,7 @@ func TestFileChecker(t *testing.T) {
 	}
 
 	// Create all variables necessary for the test
-	h, err := New("sha256")
+	h, err := New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 was not a valid hash for creating hasher")
 	}
@@ -175,7 +177,7 @@ func TestFileChecker(t *testing.T) {
 	wg.Add(1)
 
 	// Do the actual test
-	h.fileChecker(threadSafe.NewStringList(fileList), &fuse, &wg)
+	h.fileChecker(context.Background(), threadSafe.NewStringList(fileList), &fuse, &wg)
 
 	// Check if there are false positives
 	if fuse {
@@ -185,7 +187,7 @@ func TestFileChecker(t *testing.T) {
 	// Test it now for real errors
 	fileList = append(fileList, filepath.Join(tmpDir, "random-invalid.name"))
 	wg.Add(1) // We can reuse the same not-triggered fuse and not-waited wg
-	h.fileChecker(threadSafe.NewStringList(fileList), &fuse, &wg)
+	h.fileChecker(context.Background(), threadSafe.NewStringList(fileList), &fuse, &wg)
 	if !fuse {
 		t.Fatal("Fuse was not triggered with actual errors")
 	}
@@ -195,7 +197,7 @@ func TestFileChecker(t *testing.T) {
 
 func TestFileGetter(t *testing.T) {
 	// Create hasher
-	h, err := New("sha256")
+	h, err := New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 was not a valid hash for creating hasher")
 	}
@@ -208,7 +210,7 @@ func TestFileGetter(t *testing.T) {
 	}
 
 	// Get files (actual test)
-	if err = h.fileGetter(threadSafe.NewStringList(pathList), fileList); err != nil {
+	if err = h.fileGetter(context.Background(), threadSafe.NewStringList(pathList), fileList); err != nil {
 		t.Fatalf("error getting files: %s", err)
 	}
 
@@ -249,13 +251,13 @@ func TestFileHasher(t *testing.T) {
 	safeFileList := threadSafe.NewFileList(fileList)
 
 	// Create hasher
-	h, err := New("sha256")
+	h, err := New("sha256", nil)
 	if err != nil {
 		t.Fatal("sha256 was not a valid hash for creating hasher")
 	}
 
 	// Do the actual test
-	if err = h.fileHasher(safeFileList); err != nil {
+	if err = h.fileHasher(context.Background(), safeFileList, progress.NewTracker("hash", nil)); err != nil {
 		t.Fatalf("error hashing files: %s", err)
 	}
 
@@ -268,3 +270,24 @@ func TestFileHasher(t *testing.T) {
 		}
 	}
 }
+
+func TestFileHasher_Cancelled(t *testing.T) {
+	f, err := files.NewFile(hashes[0].path)
+	if err != nil {
+		t.Fatalf("error creating file in path \"%s\": %s", hashes[0].path, err)
+	}
+
+	h, err := New("sha256", nil)
+	if err != nil {
+		t.Fatal("sha256 was not a valid hash for creating hasher")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = h.fileHasher(ctx, threadSafe.NewFileList([]*files.File{f}), progress.NewTracker("hash", nil)); err != context.Canceled {
+		t.Errorf("expected context.Canceled, found %v", err)
+	}
+	if f.Hash != nil {
+		t.Error("file hashed after cancelling")
+	}
+}
@@ -0,0 +1,121 @@
+// Package hooks runs the commands configured to be executed around an operation,
+// like quiescing a database before a backup or sending a notification after it.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Events in which the hooks are run
+const (
+	PreBackup  = "pre-backup"  // Before listing the files to back up
+	PostBackup = "post-backup" // After the snapshot is written
+	OnFailure  = "on-failure"  // When the backup or its pre-backup hook fails
+)
+
+// Policy represents what to do when a hook fails.
+type Policy string
+
+// Supported failure policies
+const (
+	PolicyAbort    Policy = "abort"    // Fail the operation
+	PolicyContinue Policy = "continue" // Log the error and continue
+)
+
+// Hooks represents the commands that are run in each event of an operation. They are run with the shell
+// of the system, with the environment of the process plus the variables that describe the event (see Env).
+// Empty commands are not run.
+type Hooks struct {
+	PreBackup  string
+	PostBackup string
+	OnFailure  string
+	Timeout    time.Duration // Time after which a command is killed, 0 for none
+	OnError    Policy        // What to do when a command fails, empty means PolicyAbort
+	Output     io.Writer     // Where the output of the commands is written, nil discards it
+}
+
+// Env represents the variables that describe an event to its hook.
+// They are set in its environment with the prefix "GKUP_" (e.g. GKUP_SNAPSHOT).
+type Env map[string]string
+
+// ParsePolicy returns the Policy of the name provided.
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case PolicyAbort, PolicyContinue:
+		return p, nil
+	}
+	return "", fmt.Errorf("hook failure policy \"%s\" not supported", name)
+}
+
+// Command returns the command of the event provided. It returns an empty string if the hooks are nil
+// or the event doesn't have a command.
+func (h *Hooks) Command(event string) string {
+	if h == nil {
+		return ""
+	}
+	switch event {
+	case PreBackup:
+		return h.PreBackup
+	case PostBackup:
+		return h.PostBackup
+	case OnFailure:
+		return h.OnFailure
+	}
+	return ""
+}
+
+// Abort returns whether an operation must fail when one of its hooks fails.
+func (h *Hooks) Abort() bool {
+	return h == nil || h.OnError != PolicyContinue
+}
+
+// Run runs the command of the event provided, if any, with the variables provided and GKUP_HOOK set to the event.
+// The command is killed if the context provided is cancelled or it exceeds the timeout of the hooks.
+// It returns an error if the command cannot be started or doesn't exit successfully.
+func (h *Hooks) Run(ctx context.Context, event string, env Env) error {
+	command := h.Command(event)
+	if command == "" {
+		return nil
+	}
+
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	cmd := newCommand(command)
+	cmd.Stdout = h.Output
+	cmd.Stderr = h.Output
+	cmd.Env = append(os.Environ(), "GKUP_HOOK="+event)
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, "GKUP_"+k+"="+v)
+	}
+
+	// The processes started by the command are killed too, so they don't keep its output open
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s hook cannot be started: %w", event, err)
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killCommand(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s hook timed out after %s", event, h.Timeout)
+		}
+		return fmt.Errorf("%s hook failed: %w", event, err)
+	}
+	return nil
+}
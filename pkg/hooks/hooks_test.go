@@ -0,0 +1,51 @@
+package hooks_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestHooks_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are tested with sh")
+	}
+
+	output := bytes.NewBuffer(nil)
+	h := &hooks.Hooks{
+		PreBackup:  `echo "$GKUP_HOOK $GKUP_SNAPSHOT"`,
+		PostBackup: "exit 3",
+		OnFailure:  "sleep 5",
+		Timeout:    100 * time.Millisecond,
+		Output:     output,
+	}
+
+	if err := h.Run(context.Background(), hooks.PreBackup, hooks.Env{"SNAPSHOT": "test"}); err != nil {
+		t.Errorf("error running hook: %s", err)
+	}
+	if output.String() != "pre-backup test\n" {
+		t.Errorf("unexpected output of hook: %q", output.String())
+	}
+	if err := h.Run(context.Background(), hooks.PostBackup, nil); err == nil {
+		t.Error("not error running a failing hook")
+	}
+	start := time.Now()
+	if err := h.Run(context.Background(), hooks.OnFailure, nil); err == nil {
+		t.Error("not error running a hook that times out")
+	}
+	if time.Since(start) > 4*time.Second {
+		t.Error("hook not killed after its timeout")
+	}
+
+	// Nil hooks
+	var nilHooks *hooks.Hooks
+	if err := nilHooks.Run(context.Background(), hooks.PreBackup, nil); err != nil {
+		t.Errorf("error running nil hooks: %s", err)
+	}
+	if !nilHooks.Abort() || (&hooks.Hooks{OnError: hooks.PolicyContinue}).Abort() {
+		t.Error("unexpected failure policy")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package hooks
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// newCommand returns the command provided to be run with sh in its own process group.
+func newCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// killCommand kills the process group of the command provided.
+func killCommand(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
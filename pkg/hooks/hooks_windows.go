@@ -0,0 +1,13 @@
+package hooks
+
+import "os/exec"
+
+// newCommand returns the command provided to be run with cmd.
+func newCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+// killCommand kills the process of the command provided.
+func killCommand(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/logolang"
+)
+
+// minBufferSize is the minimum size of the buffers used in the operations
+const minBufferSize = 512
+
+// Options represents the settings of an operation, so several operations with different settings
+// can run in the same process.
+type Options struct {
+	BufferSize      int               // Size, in bytes, of the buffers used per thread
+	NumberOfThreads int               // Number of threads used in parallel operations
+	CopyWorkers     int               // Number of files copied to the repository at the same time in backups, NumberOfThreads if not positive
+	OmitHidden      bool              // Omit hidden files when listing the files to back up
+	Exclude         []string          // Omit the files whose name matches any of these patterns (see filepath.Match)
+	OmitErrors      bool              // Log non-critical errors and continue instead of failing
+	Paranoid        bool              // Compare byte by byte the files that are already stored in the repository
+	Log             *logolang.Logger  // Logger where the operation writes its messages
+	Progress        progress.Renderer // Renderer of the progress events of the operation, nil for none
+	Hooks           *hooks.Hooks      // Commands run around the operations that support them (backup), nil for none
+
+	// OnSkip is called for every path omitted when listing files, with the reason why it was omitted.
+	// It can be nil.
+	OnSkip func(path, reason string)
+}
+
+// NewOptions returns the Options defined by the package variables, that act as defaults.
+func NewOptions() *Options {
+	return &Options{
+		BufferSize:      BufferSize,
+		NumberOfThreads: NumberOfThreads,
+		CopyWorkers:     CopyWorkers,
+		OmitHidden:      OmitHidden,
+		Exclude:         Exclude,
+		OmitErrors:      OmitErrors,
+		Paranoid:        Paranoid,
+		Log:             Log,
+		Progress:        Progress,
+	}
+}
+
+// Normalize returns a copy of the options with the values that are not valid replaced by valid ones.
+// If the options are nil, it returns the default options (see NewOptions).
+func (o *Options) Normalize() *Options {
+	if o == nil {
+		return NewOptions().Normalize()
+	}
+
+	n := *o
+	if n.BufferSize < minBufferSize {
+		n.BufferSize = minBufferSize
+	}
+	if n.NumberOfThreads < 1 {
+		n.NumberOfThreads = 1
+	}
+	if n.CopyWorkers < 1 {
+		n.CopyWorkers = n.NumberOfThreads
+	}
+	if n.Log == nil {
+		n.Log = logolang.NewLogger()
+		n.Log.Level = logolang.LevelNoLog
+	}
+	if n.OnSkip == nil {
+		n.OnSkip = func(string, string) {}
+	}
+	return &n
+}
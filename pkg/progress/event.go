@@ -0,0 +1,29 @@
+package progress
+
+// Types of event
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventFinish   = "finish"
+)
+
+// Event represents the progress of a long-running operation at a given moment.
+type Event struct {
+	Type        string  `json:"type"`
+	Operation   string  `json:"operation"`
+	Files       int64   `json:"files"`
+	TotalFiles  int64   `json:"total_files"`
+	Bytes       int64   `json:"bytes"`
+	TotalBytes  int64   `json:"total_bytes"`
+	Elapsed     float64 `json:"elapsed_seconds"`
+	Throughput  float64 `json:"bytes_per_second"`
+	ETA         float64 `json:"eta_seconds"` // Negative if unknown
+	CurrentFile string  `json:"current_file,omitempty"`
+}
+
+// Renderer is the interface that wraps the Render method.
+//
+// Render shows the event provided. It's never called concurrently by a Tracker.
+type Renderer interface {
+	Render(e *Event)
+}
@@ -0,0 +1,85 @@
+package progress_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracker_NDJSON(t *testing.T) {
+	output := bytes.NewBuffer(nil)
+	tracker := progress.NewTracker("test", progress.NewNDJSONRenderer(output))
+	tracker.SetTotal(100, 1000)
+	tracker.Start(time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			for j := 0; j < 10; j++ {
+				tracker.SetCurrentFile("file")
+				tracker.Add(1, 10)
+				time.Sleep(time.Millisecond)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	tracker.Finish()
+
+	events := make([]progress.Event, 0, 100)
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		var e progress.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("cannot unmarshal event %s: %s", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+
+	if len(events) < 2 {
+		t.Fatalf("expected at least 2 events, found %d", len(events))
+	}
+	if events[0].Type != progress.EventStart || events[0].Files != 0 || events[0].TotalFiles != 100 {
+		t.Errorf("unexpected start event: %+v", events[0])
+	}
+	for _, e := range events[1 : len(events)-1] {
+		if e.Type != progress.EventProgress || e.Operation != "test" || e.Files > e.TotalFiles || e.Bytes > e.TotalBytes {
+			t.Errorf("unexpected progress event: %+v", e)
+		}
+	}
+	last := events[len(events)-1]
+	if last.Type != progress.EventFinish || last.Files != 100 || last.Bytes != 1000 || last.ETA != 0 || last.CurrentFile != "" {
+		t.Errorf("unexpected finish event: %+v", last)
+	}
+}
+
+func TestTracker_Text(t *testing.T) {
+	output := bytes.NewBuffer(nil)
+	tracker := progress.NewTracker("test", progress.NewTextRenderer(output))
+	tracker.SetTotal(2, 2048)
+	tracker.Start(time.Hour)
+	tracker.Add(2, 2048)
+	tracker.Finish()
+
+	expected := regexp.MustCompile(`^\rtest: 2 of 2 files, 2\.00 KiB of 2\.00 KiB \(.+/s\), ETA 0s\n$`)
+	if !expected.Match(output.Bytes()) {
+		t.Errorf("unexpected text output: %q", output.String())
+	}
+}
+
+func TestTracker_NilRenderer(t *testing.T) {
+	tracker := progress.NewTracker("test", nil)
+	tracker.Start(time.Millisecond)
+	tracker.Add(1, 1)
+	tracker.Finish()
+
+	if e := tracker.Event(progress.EventFinish); e.Files != 1 || e.Bytes != 1 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// textRenderer is a Renderer that writes the events in a single line that is rewritten with every event.
+type textRenderer struct {
+	w io.Writer
+}
+
+// ndjsonRenderer is a Renderer that writes every event as a JSON object in its own line.
+type ndjsonRenderer struct {
+	w io.Writer
+}
+
+// NewTextRenderer returns a Renderer that writes the events in an human-readable way in the writer provided.
+func NewTextRenderer(w io.Writer) Renderer {
+	return &textRenderer{w: w}
+}
+
+// NewNDJSONRenderer returns a Renderer that writes the events as newline-delimited JSON in the writer provided.
+func NewNDJSONRenderer(w io.Writer) Renderer {
+	return &ndjsonRenderer{w: w}
+}
+
+func (r *textRenderer) Render(e *Event) {
+	if e.Type == EventStart {
+		return
+	}
+
+	eta := "unknown"
+	if e.ETA >= 0 {
+		eta = (time.Duration(e.ETA) * time.Second).String()
+	}
+	_, _ = fmt.Fprintf(r.w, "\r%s: %d of %d files, %s of %s (%s/s), ETA %s",
+		e.Operation, e.Files, e.TotalFiles, formatSize(e.Bytes), formatSize(e.TotalBytes),
+		formatSize(int64(e.Throughput)), eta)
+
+	if e.Type == EventFinish {
+		_, _ = r.w.Write([]byte{'\n'})
+	}
+}
+
+func (r *ndjsonRenderer) Render(e *Event) {
+	data, _ := json.Marshal(e)
+	_, _ = r.w.Write(append(data, '\n'))
+}
+
+// formatSize returns the size provided in a human-readable way.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,123 @@
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval is the default time between progress events.
+const DefaultInterval = time.Second
+
+// Tracker keeps the progress of an operation and sends it periodically to a Renderer.
+// It's safe for concurrent use.
+type Tracker struct {
+	files, bytes           int64 // Accessed atomically, must be 64-bit aligned
+	totalFiles, totalBytes int64
+
+	operation string
+	renderer  Renderer
+	start     time.Time
+	current   atomic.Value
+	quit      chan bool
+	done      chan bool
+	mutex     sync.Mutex
+}
+
+// NewTracker creates a new Tracker for the operation provided that will send its events to the Renderer provided.
+// If the renderer is nil, no event will be sent.
+func NewTracker(operation string, r Renderer) *Tracker {
+	t := &Tracker{
+		operation: operation,
+		renderer:  r,
+		quit:      make(chan bool),
+		done:      make(chan bool),
+	}
+	t.current.Store("")
+	return t
+}
+
+// SetTotal sets the total number of files and bytes that the operation will process.
+// It must be called before Start.
+func (t *Tracker) SetTotal(files, bytes int64) {
+	t.totalFiles, t.totalBytes = files, bytes
+}
+
+// Start sends the start event and then sends a progress event every interval provided until Finish is called.
+func (t *Tracker) Start(interval time.Duration) {
+	t.start = time.Now()
+	t.render(EventStart)
+	if t.renderer == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.quit:
+				t.done <- true
+				return
+			case <-ticker.C:
+				t.render(EventProgress)
+			}
+		}
+	}()
+}
+
+// Finish stops sending progress events and sends the finish event.
+func (t *Tracker) Finish() {
+	if t.renderer != nil {
+		t.quit <- true
+		<-t.done
+	}
+	t.SetCurrentFile("")
+	t.render(EventFinish)
+}
+
+// Add adds the number of files and bytes provided to the ones processed.
+func (t *Tracker) Add(files, bytes int64) {
+	atomic.AddInt64(&t.files, files)
+	atomic.AddInt64(&t.bytes, bytes)
+}
+
+// SetCurrentFile sets the path of the file that is being processed.
+func (t *Tracker) SetCurrentFile(path string) {
+	t.current.Store(path)
+}
+
+// Event returns the current state of the operation.
+func (t *Tracker) Event(eventType string) *Event {
+	e := &Event{
+		Type:        eventType,
+		Operation:   t.operation,
+		Files:       atomic.LoadInt64(&t.files),
+		TotalFiles:  t.totalFiles,
+		Bytes:       atomic.LoadInt64(&t.bytes),
+		TotalBytes:  t.totalBytes,
+		Elapsed:     time.Since(t.start).Seconds(),
+		ETA:         -1,
+		CurrentFile: t.current.Load().(string),
+	}
+
+	if e.Elapsed > 0 {
+		e.Throughput = float64(e.Bytes) / e.Elapsed
+	}
+	if e.Throughput > 0 && e.TotalBytes >= e.Bytes {
+		e.ETA = float64(e.TotalBytes-e.Bytes) / e.Throughput
+	}
+	return e
+}
+
+// render sends an event of the type provided to the renderer, if any.
+func (t *Tracker) render(eventType string) {
+	if t.renderer == nil {
+		return
+	}
+	e := t.Event(eventType)
+
+	t.mutex.Lock()
+	t.renderer.Render(e)
+	t.mutex.Unlock()
+}
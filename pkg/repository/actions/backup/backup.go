@@ -0,0 +1,542 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"github.com/Miguel-Dorta/logolang"
+	"golang.org/x/sync/errgroup"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Backup takes the repo path, stores the files of the paths provided in the repo and writes a snapshot
+// with the name provided that reflects their tree. Only the files whose content is not already in the repo
+// are copied. When paranoid mode is enabled in the options provided or the hash algorithm of the repo is not secure,
+// the files that are already in the repo are compared byte by byte with the stored ones, and they are stored
+// with a collision index if they differ.
+// If the context provided is cancelled, it stops and writes a snapshot with the files stored until then
+// marked as incomplete, returning the error of the context.
+// The hooks of the options provided are run before listing the files, after writing the snapshot
+// and when it fails, with variables that describe the repo, the snapshot and the result.
+// It reports its progress to the renderer of the options provided, and writes a report of the backup in the writer
+// provided in an human-readable way or in JSON depending of the bool provided.
+func Backup(ctx context.Context, path, name string, paths []string, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	report, err := Run(ctx, path, name, paths, opts)
+	if report == nil {
+		return err
+	}
+	if err2 := writeReport(report, inJson, writeTo); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// DryRun is like Backup, but it doesn't modify the repo. It lists and hashes the files of the paths provided
+// and writes a report of the files that would be stored, the ones whose content is already in the repo
+// and the ones that would be skipped.
+func DryRun(ctx context.Context, path, name string, paths []string, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	report, err := Estimate(ctx, path, name, paths, opts)
+	if err != nil {
+		return err
+	}
+	return writeReport(report, inJson, writeTo)
+}
+
+// Run is like Backup, but it returns the report of the backup instead of writing it.
+// If the backup succeeds but its post-backup hook fails, it returns the report along with the error of the hook.
+func Run(ctx context.Context, path, name string, paths []string, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	return withHooks(ctx, path, name, opts, func() (*Report, error) {
+		return run(ctx, path, name, paths, false, opts)
+	})
+}
+
+// Estimate is like DryRun, but it returns the report instead of writing it.
+func Estimate(ctx context.Context, path, name string, paths []string, opts *pkg.Options) (*Report, error) {
+	return run(ctx, path, name, paths, true, opts)
+}
+
+// writeReport writes the report provided in the writer provided in an human-readable way or in JSON
+// depending of the bool provided.
+func writeReport(report *Report, inJson bool, writeTo io.Writer) error {
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	return nil
+}
+
+// run does a backup as described in Backup, or a dry run as described in DryRun if the bool provided is true.
+func run(ctx context.Context, path, name string, paths []string, dryRun bool, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	report := &Report{
+		DryRun:  dryRun,
+		Skipped: make([]SkippedFile, 0, pkg.SliceSmallCapacity),
+	}
+	onSkip := opts.OnSkip
+	opts.OnSkip = func(path, reason string) {
+		report.Skipped = append(report.Skipped, SkippedFile{Path: path, Reason: reason})
+		onSkip(path, reason)
+	}
+	id := snapshots.NewID(name, time.Now())
+
+	s, err := newStorer(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(id.Path(path)); err == nil && !dryRun {
+		return nil, fmt.Errorf("snapshot %s already exists", id)
+	}
+
+	// List all files and directories
+	opts.Log.Info("Listing files")
+	root, fileList, err := listPaths(paths, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+
+	// Hash and store all files
+	if dryRun {
+		s.planned = make(map[string]bool, len(fileList))
+	}
+	report.Snapshot = id.String()
+	failed, err := s.ingest(fileList, report, opts)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return report, ctx.Err()
+	}
+
+	// Keep only the files added if it was interrupted or some of them failed
+	if ctx.Err() != nil {
+		opts.Log.Info("Backup interrupted, saving incomplete snapshot")
+	}
+	if len(failed) != 0 {
+		root = pruneDir(root, failed)
+	}
+
+	// Write snapshot
+	opts.Log.Info("Saving snapshot")
+	if err := snapshots.Write(id.Path(path), &snapshots.Snapshot{
+		Version:    internal.Version,
+		Incomplete: ctx.Err() != nil,
+		Dirs:       root.Dirs,
+		Files:      root.Files,
+	}); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("backup interrupted, incomplete snapshot %s saved: %w", id, err)
+	}
+	return report, nil
+}
+
+// storer stores files in a repository. It's safe for concurrent use by several workers.
+type storer struct {
+	ctx        context.Context
+	repoPath   string
+	algorithm  string
+	newHash    func() hash.Hash
+	bufferSize int
+	log        *logolang.Logger
+	paranoid   bool
+	planned    map[string]bool // Objects that would be stored, only in dry runs
+
+	mutex sync.Mutex // Serializes the commits of objects and the access to planned
+}
+
+// storeWorker holds the hash and the buffer used by a goroutine that stores files.
+type storeWorker struct {
+	h   hash.Hash
+	buf []byte
+}
+
+// newStorer returns a storer that stores files in the repo of the path provided until the context provided
+// is cancelled, according to the options provided.
+func newStorer(ctx context.Context, path string, opts *pkg.Options) (*storer, error) {
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+	alg, err := hasher.GetAlgorithm(sett.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storer{
+		ctx:        ctx,
+		repoPath:   path,
+		algorithm:  sett.HashAlgorithm,
+		newHash:    alg.New,
+		bufferSize: opts.BufferSize,
+		log:        opts.Log,
+		paranoid:   opts.Paranoid || !alg.Secure,
+	}, nil
+}
+
+// newWorker returns a new storeWorker for the storer provided.
+func (s *storer) newWorker() *storeWorker {
+	return &storeWorker{
+		h:   s.newHash(),
+		buf: make([]byte, s.bufferSize),
+	}
+}
+
+// ingest hashes the files provided and stores them in the repository, adding them to the report provided.
+// The files are hashed by NumberOfThreads goroutines and stored by CopyWorkers goroutines at the same time.
+// The files whose size doesn't match any object of the repository cannot be in it yet, so they are sent directly
+// to the copy workers and hashed while they are copied, reading them only once. The rest are hashed first,
+// so only the ones that are not in the repository are copied.
+// It returns the files that were not stored, because they failed and the options provided omit errors
+// or because the context of the storer was cancelled.
+func (s *storer) ingest(fileList []*pkgFiles.File, report *Report, opts *pkg.Options) (map[*pkgFiles.File]bool, error) {
+	var totalSize int64
+	for _, f := range fileList {
+		totalSize += f.Size
+	}
+
+	// Files that can be copied without hashing them first
+	var sizes map[int64]bool
+	if s.planned == nil {
+		var err error
+		if sizes, err = s.objectSizes(); err != nil {
+			return nil, err
+		}
+	}
+	toHash := make([]*pkgFiles.File, 0, len(fileList))
+	toCopy := make([]*pkgFiles.File, 0, len(fileList))
+	for _, f := range fileList {
+		if sizes == nil || sizes[f.Size] {
+			toHash = append(toHash, f)
+		} else {
+			toCopy = append(toCopy, f)
+		}
+	}
+
+	opts.Log.Info("Adding files to repo")
+	tracker := progress.NewTracker("backup", opts.Progress)
+	tracker.SetTotal(int64(len(fileList)), totalSize)
+	tracker.Start(progress.DefaultInterval)
+	defer tracker.Finish()
+
+	var mutex sync.Mutex // Guards the report, the options' callbacks and stored
+	stored := make(map[*pkgFiles.File]bool, len(fileList))
+	skip := func(f *pkgFiles.File, err error) error {
+		tracker.Add(1, f.Size)
+		if s.ctx.Err() != nil {
+			return nil
+		}
+		if !opts.OmitErrors {
+			return err
+		}
+		mutex.Lock()
+		opts.Log.Error(err.Error())
+		opts.OnSkip(f.RealPath, err.Error())
+		mutex.Unlock()
+		return nil
+	}
+
+	eg, egCtx := errgroup.WithContext(s.ctx)
+	pending := make(chan *pkgFiles.File, opts.CopyWorkers)
+	send := func(f *pkgFiles.File) bool {
+		select {
+		case pending <- f:
+			return true
+		case <-egCtx.Done():
+			return false
+		}
+	}
+
+	// Hash workers
+	var producers sync.WaitGroup
+	hashList := threadSafe.NewFileList(toHash)
+	for i := 0; i < opts.NumberOfThreads; i++ {
+		producers.Add(1)
+		eg.Go(func() error {
+			defer producers.Done()
+			h, err := hasher.New(s.algorithm, opts)
+			if err != nil {
+				return err
+			}
+
+			for egCtx.Err() == nil {
+				f := hashList.Next()
+				if f == nil {
+					break
+				}
+				if err := h.HashFile(f); err != nil {
+					if err := skip(f, err); err != nil {
+						return err
+					}
+					continue
+				}
+				if !send(f) {
+					break
+				}
+			}
+			return nil
+		})
+	}
+	producers.Add(1)
+	go func() {
+		defer producers.Done()
+		for _, f := range toCopy {
+			if !send(f) {
+				return
+			}
+		}
+	}()
+	go func() {
+		producers.Wait()
+		close(pending)
+	}()
+
+	// Copy workers
+	for i := 0; i < opts.CopyWorkers; i++ {
+		eg.Go(func() error {
+			w := s.newWorker()
+			for f := range pending {
+				if egCtx.Err() != nil {
+					continue
+				}
+
+				tracker.SetCurrentFile(f.RealPath)
+				isNew, err := s.storeFile(w, f)
+				if err != nil {
+					if err := skip(f, err); err != nil {
+						return err
+					}
+					continue
+				}
+				tracker.Add(1, f.Size)
+
+				mutex.Lock()
+				stored[f] = true
+				report.Files++
+				report.Bytes += f.Size
+				if isNew {
+					report.NewFiles++
+					report.NewBytes += f.Size
+				}
+				mutex.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil && s.ctx.Err() == nil {
+		return nil, err
+	}
+
+	failed := make(map[*pkgFiles.File]bool)
+	for _, f := range fileList {
+		if !stored[f] {
+			failed[f] = true
+		}
+	}
+	return failed, nil
+}
+
+// objectSizes returns the sizes of the objects of the repository.
+func (s *storer) objectSizes() (map[int64]bool, error) {
+	objList, err := files.List(s.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[int64]bool, len(objList))
+	for _, objPath := range objList {
+		if _, size, err := files.GetDataFromName(filepath.Base(objPath)); err == nil {
+			sizes[size] = true
+		}
+	}
+	return sizes, nil
+}
+
+// storeFile stores the file provided in the repository if its object doesn't exist yet, returning whether it was stored.
+// If the file was not hashed yet, it's hashed while it's copied. In dry runs, nothing is stored and it returns
+// whether it would be stored. In paranoid mode, if an object with the same hash and size exists but its content
+// is different, the file is stored with the next free collision index, that will be saved in the files.File provided.
+func (s *storer) storeFile(w *storeWorker, f *pkgFiles.File) (bool, error) {
+	if f.Hash != nil {
+		exists, err := s.findObject(w, f)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+
+	if s.planned != nil {
+		if f.Hash == nil {
+			return false, fmt.Errorf("%s could not be hashed", f.RealPath)
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		objName := files.GetObjectName(f)
+		if s.planned[objName] {
+			s.log.Debugf("%s would be already in the repo", f.RealPath)
+			return false, nil
+		}
+		s.log.Debugf("%s would be added to the repo", f.RealPath)
+		s.planned[objName] = true
+		return true, nil
+	}
+
+	s.log.Debugf("Adding %s to repo", f.RealPath)
+	return s.copyFile(w, f)
+}
+
+// findObject returns whether the hashed file provided is already in the repository.
+// In paranoid mode, the objects with the same hash and size are compared byte by byte with the file,
+// and the index of the first free collision is saved in the files.File provided if none of them is equal.
+func (s *storer) findObject(w *storeWorker, f *pkgFiles.File) (bool, error) {
+	for {
+		objPath := files.GetObjectPath(s.repoPath, f)
+
+		_, err := os.Stat(objPath)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, &os.PathError{
+				Op:   "stat object",
+				Path: objPath,
+				Err:  err,
+			}
+		}
+		if !s.paranoid {
+			s.log.Debugf("%s is already in the repo, omitting", f.RealPath)
+			return true, nil
+		}
+
+		half := len(w.buf) / 2
+		equal, err := utils.EqualFiles(f.RealPath, objPath, w.buf[:half], w.buf[half:2*half])
+		if err != nil {
+			return false, err
+		}
+		if equal {
+			s.log.Debugf("%s is already in the repo, omitting", f.RealPath)
+			return true, nil
+		}
+		s.log.Infof("Hash collision found between %s and %s", f.RealPath, objPath)
+		f.Collision++
+	}
+}
+
+// copyFile copies the file provided to the repository, returning whether it was stored. If the file was hashed already,
+// it verifies that its content didn't change since then. Otherwise, it saves the hash in the files.File provided.
+func (s *storer) copyFile(w *storeWorker, f *pkgFiles.File) (bool, error) {
+	src, err := os.Open(f.RealPath)
+	if err != nil {
+		return false, &os.PathError{
+			Op:   "open file",
+			Path: f.RealPath,
+			Err:  err,
+		}
+	}
+	defer src.Close()
+
+	obj, err := files.NewTempObject(s.repoPath, w.h)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.CopyBuffer(obj, utils.NewContextReader(s.ctx, src), w.buf); err != nil {
+		_ = obj.Discard()
+		return false, fmt.Errorf("error copying %s: %w", f.RealPath, err)
+	}
+
+	h, size := obj.Sum()
+	if size != f.Size || f.Hash != nil && !bytes.Equal(h, f.Hash) {
+		_ = obj.Discard()
+		return false, fmt.Errorf("%s changed while it was being backed up", f.RealPath)
+	}
+	f.Hash = h
+	return s.commit(w, obj, f, f.RealPath)
+}
+
+// commit moves the temporary object provided, whose hash and size must be the ones of the files.File provided,
+// to the repository if an equal object doesn't exist yet, returning whether it was stored. In paranoid mode,
+// the objects with the same hash and size are compared byte by byte with it, and it's stored with the first free
+// collision index, that will be saved in the files.File provided. The source provided is the name of the content
+// in the messages logged.
+func (s *storer) commit(w *storeWorker, obj *files.TempObject, f *pkgFiles.File, source string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f.Collision = 0
+	for {
+		objPath := files.GetObjectPath(s.repoPath, f)
+
+		_, err := os.Stat(objPath)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			_ = obj.Discard()
+			return false, &os.PathError{
+				Op:   "stat object",
+				Path: objPath,
+				Err:  err,
+			}
+		}
+		if !s.paranoid {
+			s.log.Debugf("%s is already in the repo, omitting", source)
+			return false, obj.Discard()
+		}
+
+		half := len(w.buf) / 2
+		equal, err := utils.EqualFiles(obj.Path(), objPath, w.buf[:half], w.buf[half:2*half])
+		if err != nil {
+			_ = obj.Discard()
+			return false, err
+		}
+		if equal {
+			s.log.Debugf("%s is already in the repo, omitting", source)
+			return false, obj.Discard()
+		}
+		s.log.Infof("Hash collision found between %s and %s", source, objPath)
+		f.Collision++
+	}
+
+	if _, _, err := obj.CommitCollision(f.Collision); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// pruneDir returns a copy of the files.Dir provided without the files of the set provided.
+func pruneDir(d pkgFiles.Dir, drop map[*pkgFiles.File]bool) pkgFiles.Dir {
+	pruned := d
+	pruned.Files = make([]*pkgFiles.File, 0, len(d.Files))
+	pruned.Dirs = make([]pkgFiles.Dir, 0, len(d.Dirs))
+	for _, f := range d.Files {
+		if !drop[f] {
+			pruned.Files = append(pruned.Files, f)
+		}
+	}
+	for _, child := range d.Dirs {
+		pruned.Dirs = append(pruned.Dirs, pruneDir(child, drop))
+	}
+	return pruned
+}
@@ -0,0 +1,509 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/backup"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestBackup")
+	repoPath    = filepath.Join(testingPath, "repo")
+	srcPath     = filepath.Join(testingPath, "src")
+)
+
+var opts = &pkg.Options{
+	BufferSize:      512,
+	NumberOfThreads: 2,
+	Exclude:         []string{"*.tmp"},
+}
+
+var srcFiles = map[string]string{
+	"a":         "first content",
+	"dir/b":     "second content",
+	"dir/c":     "first content",
+	"dir/d.tmp": "excluded content",
+	"e":         "third content",
+}
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestBackup(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	paths := []string{filepath.Join(srcPath, "dir"), filepath.Join(srcPath, "a")}
+	report := doBackup("first", paths, t)
+	if report.Files != 3 || report.Bytes != 40 || report.NewFiles != 2 || report.NewBytes != 27 {
+		t.Errorf("unexpected report of first backup: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"a": "first content", "dir/b": "second content", "dir/c": "first content"}, t)
+
+	report = doBackup("second", []string{srcPath}, t)
+	if report.Files != 4 || report.Bytes != 53 || report.NewFiles != 1 || report.NewBytes != 13 {
+		t.Errorf("unexpected report of second backup: %+v", report)
+	}
+
+	if err := check.Check(context.Background(), repoPath, opts, true, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Errorf("error checking repository after backups: %s", err)
+	}
+
+	// Not existing path
+	if err := backup.Backup(context.Background(), repoPath, "missing", []string{filepath.Join(srcPath, "missing")}, opts, true, ioutil.Discard); err == nil {
+		t.Error("not error backing up a path that doesn't exist")
+	}
+
+	// Cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := backup.Backup(ctx, repoPath, "cancelled", []string{srcPath}, opts, true, ioutil.Discard); err == nil {
+		t.Error("not error backing up with context cancelled")
+	}
+	ids, err := snapshots.ListByName(repoPath, "cancelled")
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("incomplete snapshot not found: %v, %v", ids, err)
+	}
+	snap, err := snapshots.Read(ids[0].Path(repoPath))
+	if err != nil || !snap.Incomplete {
+		t.Fatalf("snapshot not marked as incomplete: %+v, %v", snap, err)
+	}
+	_ = snap.Walk(func(path string, _ *pkgFiles.File) error {
+		t.Errorf("file %s found in snapshot of a cancelled backup", path)
+		return nil
+	})
+	if len(snap.Dirs) == 0 {
+		t.Error("directories not found in snapshot of a cancelled backup")
+	}
+	checkDirInfo(snap.Dirs, t)
+}
+
+func TestBackup_CopyWorkers(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	// Files with the same content, files with the same size and files with sizes that are not in the repo
+	expected := make(map[string]string, 60)
+	for i := 0; i < 60; i++ {
+		content := strings.Repeat("x", i%10) + strconv.Itoa(i%30)
+		expected["src/"+strconv.Itoa(i)] = content
+		if err := os.MkdirAll(srcPath, 0755); err != nil {
+			t.Fatalf("error creating source: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(srcPath, strconv.Itoa(i)), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing source: %s", err)
+		}
+	}
+
+	workerOpts := *opts
+	workerOpts.CopyWorkers = 4
+	workerOpts.Paranoid = true
+	report, err := backup.Run(context.Background(), repoPath, "first", []string{srcPath}, &workerOpts)
+	if err != nil {
+		t.Fatalf("error backing up: %s", err)
+	}
+	if report.Files != 60 || report.NewFiles != 30 {
+		t.Errorf("unexpected report of first backup: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, expected, t)
+
+	// Change some files keeping their sizes, so they're hashed before being copied
+	for i := 0; i < 60; i += 7 {
+		content := strings.Repeat("y", i%10) + strconv.Itoa(i%30)
+		expected["src/"+strconv.Itoa(i)] = content
+		if err := ioutil.WriteFile(filepath.Join(srcPath, strconv.Itoa(i)), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing source: %s", err)
+		}
+	}
+	report, err = backup.Run(context.Background(), repoPath, "second", []string{srcPath}, &workerOpts)
+	if err != nil {
+		t.Fatalf("error backing up: %s", err)
+	}
+	if report.Files != 60 || report.NewFiles != 8 {
+		t.Errorf("unexpected report of second backup: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, expected, t)
+
+	if err := check.Check(context.Background(), repoPath, opts, true, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Errorf("error checking repository after backups: %s", err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(repoPath, "files", "tmp-*")); len(tmp) != 0 {
+		t.Errorf("temporary objects left in the repo: %v", tmp)
+	}
+}
+
+func TestBackup_DryRun(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	report, err := backup.Estimate(context.Background(), repoPath, "dry", []string{srcPath}, opts)
+	if err != nil {
+		t.Fatalf("error in dry run: %s", err)
+	}
+	if !report.DryRun || report.Files != 4 || report.Bytes != 53 || report.NewFiles != 3 || report.NewBytes != 40 {
+		t.Errorf("unexpected report of dry run: %+v", report)
+	}
+	expectedSkipped := filepath.Join(srcPath, "dir", "d.tmp")
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != expectedSkipped || report.Skipped[0].Reason != "excluded" {
+		t.Errorf("unexpected skipped files: %+v", report.Skipped)
+	}
+	if ids, err := snapshots.List(repoPath); err != nil || len(ids) != 0 {
+		t.Errorf("snapshots found after dry run: %v, %v", ids, err)
+	}
+	checkNoObjects(t)
+
+	// Dry run after a backup
+	doBackup("first", []string{filepath.Join(srcPath, "dir"), filepath.Join(srcPath, "a")}, t)
+	output := bytes.NewBuffer(nil)
+	if err := backup.DryRun(context.Background(), repoPath, "dry", []string{srcPath}, opts, true, output); err != nil {
+		t.Fatalf("error in dry run: %s", err)
+	}
+	if err := json.Unmarshal(output.Bytes(), report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if report.Files != 4 || report.NewFiles != 1 || report.NewBytes != 13 {
+		t.Errorf("unexpected report of dry run after backup: %+v", report)
+	}
+	if ids, err := snapshots.ListByName(repoPath, "dry"); err != nil || len(ids) != 0 {
+		t.Errorf("snapshots found after dry run: %v, %v", ids, err)
+	}
+
+	// Dry run with the name and time of an existing snapshot
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		id := snapshots.NewID("first", now.Add(time.Duration(i)*time.Second))
+		if err := snapshots.Write(id.Path(repoPath), &snapshots.Snapshot{}); err != nil {
+			t.Fatalf("error writing snapshot %s: %s", id, err)
+		}
+	}
+	if _, err := backup.Estimate(context.Background(), repoPath, "first", []string{srcPath}, opts); err != nil {
+		t.Errorf("error in dry run with the time of an existing snapshot: %s", err)
+	}
+}
+
+func TestBackup_Stdin(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	report, err := backup.RunStdin(context.Background(), repoPath, "first", "db.sql", strings.NewReader("first content"), opts)
+	if err != nil {
+		t.Fatalf("error backing up stream: %s", err)
+	}
+	if report.Files != 1 || report.Bytes != 13 || report.NewFiles != 1 || report.NewBytes != 13 {
+		t.Errorf("unexpected report of first backup: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"db.sql": "first content"}, t)
+
+	report, err = backup.RunStdin(context.Background(), repoPath, "second", "db.sql", strings.NewReader("first content"), opts)
+	if err != nil {
+		t.Fatalf("error backing up stream: %s", err)
+	}
+	if report.Files != 1 || report.NewFiles != 0 {
+		t.Errorf("unexpected report of second backup: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"db.sql": "first content"}, t)
+
+	if err := check.Check(context.Background(), repoPath, opts, true, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Errorf("error checking repository after backups: %s", err)
+	}
+
+	// Invalid file name
+	if _, err := backup.RunStdin(context.Background(), repoPath, "invalid", "dir/db.sql", strings.NewReader(""), opts); err == nil {
+		t.Error("not error backing up stream with an invalid file name")
+	}
+}
+
+func TestBackup_Hooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are tested with sh")
+	}
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	output := bytes.NewBuffer(nil)
+	hookOpts := *opts
+	hookOpts.Hooks = &hooks.Hooks{
+		PreBackup:  `echo "$GKUP_HOOK $GKUP_SNAPSHOT_NAME"`,
+		PostBackup: `echo "$GKUP_HOOK $GKUP_STATUS $GKUP_SNAPSHOT $GKUP_FILES $GKUP_NEW_FILES"`,
+		OnFailure:  `echo "$GKUP_HOOK $GKUP_STATUS"`,
+		Output:     output,
+	}
+	report, err := backup.Run(context.Background(), repoPath, "hooks", []string{srcPath}, &hookOpts)
+	if err != nil {
+		t.Fatalf("error backing up: %s", err)
+	}
+	expected := "pre-backup hooks\npost-backup success " + report.Snapshot + " 4 3\n"
+	if output.String() != expected {
+		t.Errorf("unexpected output of hooks: %q, expected %q", output.String(), expected)
+	}
+
+	// Pre-backup hook failing
+	output.Reset()
+	hookOpts.Hooks.PreBackup = "exit 1"
+	if _, err := backup.Run(context.Background(), repoPath, "failed", []string{srcPath}, &hookOpts); err == nil {
+		t.Error("not error backing up with a failing pre-backup hook")
+	}
+	if output.String() != "on-failure failure\n" {
+		t.Errorf("unexpected output of hooks: %q", output.String())
+	}
+	if ids, err := snapshots.ListByName(repoPath, "failed"); err != nil || len(ids) != 0 {
+		t.Errorf("snapshot found after failed pre-backup hook: %v, %v", ids, err)
+	}
+
+	// Continue on errors
+	hookOpts.Hooks.OnError = hooks.PolicyContinue
+	if _, err := backup.Run(context.Background(), repoPath, "continued", []string{srcPath}, &hookOpts); err != nil {
+		t.Errorf("error backing up with a failing hook that continues: %s", err)
+	}
+}
+
+func TestBackup_Watch(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan *backup.Report)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- backup.RunWatch(ctx, repoPath, "watch", []string{srcPath}, time.Second, opts, func(report *backup.Report) error {
+			reports <- report
+			return nil
+		})
+	}()
+
+	var report *backup.Report
+	select {
+	case report = <-reports:
+	case err := <-errs:
+		t.Fatalf("error watching: %s", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for first snapshot")
+	}
+	if report.Files != 4 || report.NewFiles != 3 {
+		t.Errorf("unexpected report of first snapshot: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"src/a": "first content", "src/dir/b": "second content", "src/dir/c": "first content", "src/e": "third content"}, t)
+
+	// Modify, add and remove files
+	if err := ioutil.WriteFile(filepath.Join(srcPath, "dir", "b"), []byte("changed content"), 0644); err != nil {
+		t.Fatalf("error modifying file: %s", err)
+	}
+	if err := os.Remove(filepath.Join(srcPath, "e")); err != nil {
+		t.Fatalf("error removing file: %s", err)
+	}
+	if err := os.Mkdir(filepath.Join(srcPath, "sub"), 0755); err != nil {
+		t.Fatalf("error creating directory: %s", err)
+	}
+	for name, content := range map[string]string{"x": "fourth content", "y.tmp": "excluded content"} {
+		if err := ioutil.WriteFile(filepath.Join(srcPath, "sub", name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing file: %s", err)
+		}
+	}
+
+	// The changes can be split between several snapshots
+	newFiles := 0
+	timeout := time.After(10 * time.Second)
+	for report.Files != 4 || newFiles != 2 {
+		select {
+		case report = <-reports:
+			newFiles += report.NewFiles
+		case err := <-errs:
+			t.Fatalf("error watching: %s", err)
+		case <-timeout:
+			t.Fatalf("timeout waiting for the changes, last report: %+v", report)
+		}
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"src/a": "first content", "src/dir/b": "changed content", "src/dir/c": "first content", "src/sub/x": "fourth content"}, t)
+
+	cancel()
+	if err := <-errs; err != nil {
+		t.Errorf("error stopping watch: %s", err)
+	}
+	if err := check.Check(context.Background(), repoPath, opts, true, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Errorf("error checking repository after watching: %s", err)
+	}
+
+	// Invalid interval
+	if err := backup.RunWatch(context.Background(), repoPath, "watch", []string{srcPath}, time.Millisecond, opts, nil); err == nil {
+		t.Error("not error watching with an invalid interval")
+	}
+}
+
+func TestBackup_WatchExcluded(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan *backup.Report)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- backup.RunWatch(ctx, repoPath, "watch", []string{srcPath}, time.Second, opts, func(report *backup.Report) error {
+			reports <- report
+			return nil
+		})
+	}()
+
+	select {
+	case <-reports:
+	case err := <-errs:
+		t.Fatalf("error watching: %s", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for first snapshot")
+	}
+
+	// Write a file inside a new excluded directory, and then a file that is backed up
+	excludedPath := filepath.Join(srcPath, "cache.tmp")
+	if err := os.Mkdir(excludedPath, 0755); err != nil {
+		t.Fatalf("error creating directory: %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := ioutil.WriteFile(filepath.Join(excludedPath, "x"), []byte("excluded content"), 0644); err != nil {
+		t.Fatalf("error writing file: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(srcPath, "f"), []byte("fourth content"), 0644); err != nil {
+		t.Fatalf("error writing file: %s", err)
+	}
+
+	var report *backup.Report
+	timeout := time.After(10 * time.Second)
+	for report == nil || report.NewFiles == 0 {
+		select {
+		case report = <-reports:
+		case err := <-errs:
+			t.Fatalf("error watching: %s", err)
+		case <-timeout:
+			t.Fatal("timeout waiting for the changes")
+		}
+	}
+	checkSnapshot(report.Snapshot, map[string]string{"src/a": "first content", "src/dir/b": "second content", "src/dir/c": "first content", "src/e": "third content", "src/f": "fourth content"}, t)
+
+	cancel()
+	if err := <-errs; err != nil {
+		t.Errorf("error stopping watch: %s", err)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func createSource(t *testing.T) {
+	for path, content := range srcFiles {
+		path = filepath.Join(srcPath, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating directory %s: %s", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %s: %s", path, err)
+		}
+	}
+}
+
+func doBackup(name string, paths []string, t *testing.T) backup.Report {
+	output := bytes.NewBuffer(nil)
+	if err := backup.Backup(context.Background(), repoPath, name, paths, opts, true, output); err != nil {
+		t.Fatalf("error backing up %s: %s", name, err)
+	}
+
+	var report backup.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	return report
+}
+
+func checkSnapshot(snapshot string, expected map[string]string, t *testing.T) {
+	id, err := snapshots.ParseID(snapshot)
+	if err != nil {
+		t.Fatalf("error parsing snapshot %s: %s", snapshot, err)
+	}
+	snap, err := snapshots.Read(id.Path(repoPath))
+	if err != nil {
+		t.Fatalf("error reading snapshot %s: %s", snapshot, err)
+	}
+
+	found := 0
+	_ = snap.Walk(func(path string, f *pkgFiles.File) error {
+		content, ok := expected[path]
+		if !ok {
+			t.Errorf("unexpected file %s in snapshot %s", path, snapshot)
+			return nil
+		}
+		found++
+
+		data, err := ioutil.ReadFile(files.GetObjectPath(repoPath, f))
+		if err != nil {
+			t.Errorf("error reading object of %s: %s", path, err)
+		} else if string(data) != content {
+			t.Errorf("content of %s doesn't match\n-> Expected: %s\n-> Found: %s", path, content, string(data))
+		}
+		return nil
+	})
+	if found != len(expected) {
+		t.Errorf("unexpected number of files in snapshot %s\n-> Expected: %d\n-> Found: %d", snapshot, len(expected), found)
+	}
+}
+
+// checkDirInfo checks that the permissions and the modification time of the directories provided
+// and their children were kept.
+func checkDirInfo(dirs []pkgFiles.Dir, t *testing.T) {
+	for _, d := range dirs {
+		if d.Mode == 0 || d.ModTime == nil {
+			t.Errorf("permissions or modification time not kept in directory %s", d.Name)
+		}
+		checkDirInfo(d.Dirs, t)
+	}
+}
+
+func checkNoObjects(t *testing.T) {
+	err := filepath.Walk(filepath.Join(repoPath, "files"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			t.Errorf("object %s found after dry run", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error walking objects: %s", err)
+	}
+}
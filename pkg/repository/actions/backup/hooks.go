@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"context"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/hooks"
+	"strconv"
+)
+
+// withHooks runs the backup function provided between the hooks of the options provided, that must be normalized.
+// The pre-backup hook is run first, and the backup is not done if it fails unless the hooks continue on errors.
+// Then, the post-backup hook is run if the backup succeeded, or the on-failure hook if it or the pre-backup hook failed.
+// The on-failure hook is run even if the context provided was cancelled, and its errors are only logged.
+// The hooks receive the repository path (GKUP_REPO) and the snapshot name (GKUP_SNAPSHOT_NAME). The post-backup
+// and on-failure hooks also receive the status (GKUP_STATUS) and, respectively, the snapshot ID and counts
+// of the report (GKUP_SNAPSHOT, GKUP_FILES, GKUP_BYTES, GKUP_NEW_FILES and GKUP_NEW_BYTES) or the error (GKUP_ERROR).
+func withHooks(ctx context.Context, repoPath, name string, opts *pkg.Options, backup func() (*Report, error)) (*Report, error) {
+	h := opts.Hooks
+	env := hooks.Env{
+		"REPO":          repoPath,
+		"SNAPSHOT_NAME": name,
+	}
+
+	if err := h.Run(ctx, hooks.PreBackup, env); err != nil {
+		if h.Abort() {
+			runFailureHook(h, env, err, opts)
+			return nil, err
+		}
+		opts.Log.Error(err.Error())
+	}
+
+	report, err := backup()
+	if err != nil {
+		runFailureHook(h, env, err, opts)
+		return nil, err
+	}
+
+	env["STATUS"] = "success"
+	env["SNAPSHOT"] = report.Snapshot
+	env["FILES"] = strconv.Itoa(report.Files)
+	env["BYTES"] = strconv.FormatInt(report.Bytes, 10)
+	env["NEW_FILES"] = strconv.Itoa(report.NewFiles)
+	env["NEW_BYTES"] = strconv.FormatInt(report.NewBytes, 10)
+	if err := h.Run(ctx, hooks.PostBackup, env); err != nil {
+		if h.Abort() {
+			return report, err
+		}
+		opts.Log.Error(err.Error())
+	}
+	return report, nil
+}
+
+// runFailureHook runs the on-failure hook of the hooks provided with the error provided, logging its errors.
+func runFailureHook(h *hooks.Hooks, env hooks.Env, failure error, opts *pkg.Options) {
+	env["STATUS"] = "failure"
+	env["ERROR"] = failure.Error()
+	if err := h.Run(context.Background(), hooks.OnFailure, env); err != nil {
+		opts.Log.Error(err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"os"
+)
+
+// listPaths returns the files.Dir that represents the root of a snapshot containing the paths provided
+// and a slice with all the files of its tree, filtered according to the options provided.
+func listPaths(paths []string, opts *pkg.Options) (pkgFiles.Dir, []*pkgFiles.File, error) {
+	fileList := make([]*pkgFiles.File, 0, pkg.SliceBigCapacity)
+	root := pkgFiles.Dir{
+		Files: make([]*pkgFiles.File, 0, pkg.SliceSmallCapacity),
+		Dirs:  make([]pkgFiles.Dir, 0, pkg.SliceSmallCapacity),
+	}
+
+	for _, path := range paths {
+		// Get info of file (and check if it exists)
+		stat, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return pkgFiles.Dir{}, nil, fmt.Errorf("\"%s\" not found", path)
+			}
+			return pkgFiles.Dir{}, nil, &os.PathError{
+				Op:   "stat path",
+				Path: path,
+				Err:  err,
+			}
+		}
+
+		// Skip if it's hidden or excluded
+		if opts.OmitHidden && utils.IsHidden(stat.Name()) {
+			opts.Log.Debugf("omitting hidden file %s", path)
+			opts.OnSkip(path, "hidden")
+			continue
+		}
+		if utils.IsExcluded(stat.Name(), opts.Exclude) {
+			opts.Log.Debugf("omitting excluded file %s", path)
+			opts.OnSkip(path, "excluded")
+			continue
+		}
+
+		if stat.Mode().IsDir() {
+			opts.Log.Debugf("Listing directory %s", path)
+			child, childFiles, err := pkgFiles.NewDir(path, opts)
+			if err != nil {
+				if opts.OmitErrors {
+					opts.Log.Error(err.Error())
+					opts.OnSkip(path, err.Error())
+					continue
+				}
+				return pkgFiles.Dir{}, nil, err
+			}
+			root.Dirs = append(root.Dirs, child)
+			fileList = append(fileList, childFiles...)
+
+		} else if stat.Mode().IsRegular() {
+			opts.Log.Debugf("Listing file %s", path)
+			child, err := pkgFiles.NewFile(path)
+			if err != nil {
+				if opts.OmitErrors {
+					opts.Log.Error(err.Error())
+					opts.OnSkip(path, err.Error())
+					continue
+				}
+				return pkgFiles.Dir{}, nil, err
+			}
+			root.Files = append(root.Files, child)
+
+		} else {
+			opts.Log.Debugf("omitting unsupported file %s", path)
+			opts.OnSkip(path, "unsupported file type")
+		}
+	}
+	fileList = append(fileList, root.Files...)
+
+	return root, fileList, nil
+}
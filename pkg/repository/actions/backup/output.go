@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of a backup.
+type Report struct {
+	Snapshot string `json:"snapshot"`
+	Files    int    `json:"files"`     // Files included in the snapshot
+	Bytes    int64  `json:"bytes"`     // Size of the files included in the snapshot
+	NewFiles int    `json:"new_files"` // Files whose content was not in the repository
+	NewBytes int64  `json:"new_bytes"` // Size of the files whose content was not in the repository
+
+	DryRun  bool          `json:"dry_run,omitempty"` // Whether nothing was stored
+	Skipped []SkippedFile `json:"skipped"`           // Files not included in the snapshot
+}
+
+// SkippedFile represents a file that was not included in a backup.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+	if report.DryRun {
+		_, _ = fmt.Fprintf(buf, "Dry run: snapshot %s would be created\nFiles to back up: %d (%d bytes)\n"+
+			"New files: %d (%d bytes)\nAlready in repository: %d (%d bytes)\n",
+			report.Snapshot, report.Files, report.Bytes, report.NewFiles, report.NewBytes,
+			report.Files-report.NewFiles, report.Bytes-report.NewBytes)
+	} else {
+		_, _ = fmt.Fprintf(buf, "Snapshot %s created\nFiles backed up: %d (%d bytes)\nNew files: %d (%d bytes)\n",
+			report.Snapshot, report.Files, report.Bytes, report.NewFiles, report.NewBytes)
+	}
+	if len(report.Skipped) != 0 {
+		_, _ = fmt.Fprintf(buf, "Files skipped: %d\n", len(report.Skipped))
+		for _, f := range report.Skipped {
+			_, _ = fmt.Fprintf(buf, "  %s (%s)\n", f.Path, f.Reason)
+		}
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Stdin takes the repo path, stores the content of the reader provided in the repo and writes a snapshot
+// with the name provided that contains it as a single file with the file name provided.
+// The content is hashed while it's written to a temporary object, so it's never read twice from the reader.
+// When paranoid mode is enabled in the options provided or the hash algorithm of the repo is not secure,
+// it's compared byte by byte with the object that has its hash, and it's stored with a collision index if they differ.
+// If the context provided is cancelled, it stops and writes an empty snapshot marked as incomplete,
+// returning the error of the context.
+// The hooks of the options provided are run as in Backup.
+// It writes a report of the backup in the writer provided in an human-readable way or in JSON
+// depending of the bool provided.
+func Stdin(ctx context.Context, path, name, fileName string, r io.Reader, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	report, err := RunStdin(ctx, path, name, fileName, r, opts)
+	if report == nil {
+		return err
+	}
+	if err2 := writeReport(report, inJson, writeTo); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// RunStdin is like Stdin, but it returns the report of the backup instead of writing it.
+// If the backup succeeds but its post-backup hook fails, it returns the report along with the error of the hook.
+func RunStdin(ctx context.Context, path, name, fileName string, r io.Reader, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	return withHooks(ctx, path, name, opts, func() (*Report, error) {
+		return runStdin(ctx, path, name, fileName, r, opts)
+	})
+}
+
+// runStdin does a backup of the reader provided as described in Stdin, without running its hooks.
+func runStdin(ctx context.Context, path, name, fileName string, r io.Reader, opts *pkg.Options) (*Report, error) {
+	id := snapshots.NewID(name, time.Now())
+
+	if fileName == "" || fileName == "." || fileName == ".." || strings.ContainsAny(fileName, `/\`) {
+		return nil, fmt.Errorf("invalid file name \"%s\"", fileName)
+	}
+	s, err := newStorer(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(id.Path(path)); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", id)
+	}
+
+	// Store content
+	opts.Log.Info("Adding standard input to repo")
+	f, stored, err := s.storeStream(s.newWorker(), r)
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("error storing %s: %w", fileName, err)
+	}
+	interrupted := err != nil
+
+	report := &Report{
+		Snapshot: id.String(),
+		Skipped:  make([]SkippedFile, 0),
+	}
+	snap := &snapshots.Snapshot{
+		Version:    internal.Version,
+		Incomplete: interrupted,
+		Dirs:       make([]pkgFiles.Dir, 0),
+		Files:      make([]*pkgFiles.File, 0, 1),
+	}
+	if !interrupted {
+		f.Name = fileName
+		snap.Files = append(snap.Files, f)
+		report.Files, report.Bytes = 1, f.Size
+		if stored {
+			report.NewFiles, report.NewBytes = 1, f.Size
+		}
+	} else {
+		opts.Log.Info("Backup interrupted, saving incomplete snapshot")
+	}
+
+	// Write snapshot
+	opts.Log.Info("Saving snapshot")
+	if err := snapshots.Write(id.Path(path), snap); err != nil {
+		return nil, err
+	}
+	if interrupted {
+		return nil, fmt.Errorf("backup interrupted, incomplete snapshot %s saved: %w", id, ctx.Err())
+	}
+	return report, nil
+}
+
+// storeStream stores the content of the reader provided in the repository if its object doesn't exist yet,
+// returning its files.File and whether it was stored.
+// In paranoid mode, if an object with the same hash and size exists but its content is different,
+// the content is stored with the next free collision index, that will be saved in the files.File returned.
+func (s *storer) storeStream(w *storeWorker, r io.Reader) (*pkgFiles.File, bool, error) {
+	obj, err := files.NewTempObject(s.repoPath, w.h)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := io.CopyBuffer(obj, utils.NewContextReader(s.ctx, r), w.buf); err != nil {
+		_ = obj.Discard()
+		return nil, false, err
+	}
+
+	hash, size := obj.Sum()
+	f := &pkgFiles.File{Hash: hash, Size: size}
+	stored, err := s.commit(w, obj, f, "standard input")
+	if err != nil {
+		return nil, false, err
+	}
+	return f, stored, nil
+}
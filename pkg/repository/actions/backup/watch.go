@@ -0,0 +1,495 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"github.com/fsnotify/fsnotify"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinWatchInterval is the minimum interval between the snapshots of Watch.
+const MinWatchInterval = time.Second
+
+// Watch takes the repo path, backs up the paths provided like Backup and keeps watching them for changes
+// with the notifications of the file system (inotify on Linux). Every interval in which any of them changed,
+// it writes a new snapshot with the name provided reusing the tree of the previous one, so only the paths
+// that changed are listed and hashed again. Errors of single files are always logged and omitted,
+// and the files are skipped until they change again. If the notifications are lost, the next snapshot
+// lists and hashes all the files again.
+// It writes a report of every snapshot in the writer provided in an human-readable way or in JSON depending of the bool
+// provided, and it runs until the context provided is cancelled. Then, it returns nil.
+func Watch(ctx context.Context, path, name string, paths []string, interval time.Duration, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	return RunWatch(ctx, path, name, paths, interval, opts, func(report *Report) error {
+		return writeReport(report, inJson, writeTo)
+	})
+}
+
+// RunWatch is like Watch, but it calls the function provided with the report of every snapshot instead of writing it.
+// If the function returns an error, it stops and returns that error.
+func RunWatch(ctx context.Context, path, name string, paths []string, interval time.Duration, opts *pkg.Options, onSnapshot func(*Report) error) error {
+	opts = opts.Normalize()
+	opts.OmitErrors = true
+	if interval < MinWatchInterval {
+		return fmt.Errorf("watch interval must be at least %s", MinWatchInterval)
+	}
+
+	s, err := newStorer(ctx, path, opts)
+	if err != nil {
+		return err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating file system watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	sources := make([]string, len(paths))
+	for i, p := range paths {
+		sources[i] = filepath.Clean(p)
+	}
+	w := &watcher{
+		storer:  s,
+		name:    name,
+		sources: sources,
+		opts:    opts,
+		fsw:     fsw,
+		changed: make(map[string]bool),
+		rescan:  true,
+	}
+	go w.collect()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Snapshots are identified by the second they were created
+		if w.pending() && time.Now().UTC().Truncate(time.Second).After(w.last) {
+			report, err := withHooks(ctx, path, name, opts, w.snapshot)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := onSnapshot(report); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watcher keeps the tree of the last snapshot of a watched backup and the paths changed since then.
+type watcher struct {
+	*storer
+	name    string
+	sources []string
+	opts    *pkg.Options
+	fsw     *fsnotify.Watcher
+	root    *node
+	last    time.Time // Time of the last snapshot
+
+	mutex   sync.Mutex
+	changed map[string]bool // Paths changed, and whether they must be listed again even if they were directories already
+	rescan  bool            // Whether all the files must be listed again
+}
+
+// node represents a directory of the tree of a watched backup.
+type node struct {
+	name    string
+	path    string
+	mode    os.FileMode
+	modTime *time.Time
+	dirs    map[string]*node
+	files   map[string]*pkgFiles.File
+}
+
+// collect records the paths changed notified by the file system watcher until it's closed.
+// If the watcher fails, all the files will be listed again in the next snapshot.
+func (w *watcher) collect() {
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			created := event.Op&fsnotify.Create != 0
+			if created && !omitted(filepath.Base(event.Name), w.opts) {
+				if stat, err := os.Lstat(event.Name); err == nil && stat.IsDir() {
+					w.watchDir(event.Name)
+				}
+			}
+			w.mutex.Lock()
+			w.changed[event.Name] = w.changed[event.Name] || created
+			w.mutex.Unlock()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.opts.Log.Errorf("Error watching files, they will be listed again: %s", err)
+			w.mutex.Lock()
+			w.rescan = true
+			w.mutex.Unlock()
+		}
+	}
+}
+
+// pending returns whether there are changes since the last snapshot.
+func (w *watcher) pending() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.rescan || len(w.changed) != 0
+}
+
+// snapshot applies the changes recorded to the tree, hashes and stores the files that changed
+// and writes a snapshot of the tree. It must be run with the options of the watcher.
+func (w *watcher) snapshot() (*Report, error) {
+	w.mutex.Lock()
+	changed, rescan := w.changed, w.rescan
+	w.changed, w.rescan = make(map[string]bool), false
+	w.mutex.Unlock()
+
+	id := snapshots.NewID(w.name, time.Now())
+	w.last = id.Time
+	if _, err := os.Stat(id.Path(w.repoPath)); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", id)
+	}
+	report := &Report{
+		Snapshot: id.String(),
+		Skipped:  make([]SkippedFile, 0, pkg.SliceSmallCapacity),
+	}
+	opts := *w.opts
+	opts.OnSkip = func(path, reason string) {
+		report.Skipped = append(report.Skipped, SkippedFile{Path: path, Reason: reason})
+		w.opts.OnSkip(path, reason)
+	}
+
+	// Update tree
+	if rescan || w.root == nil {
+		opts.Log.Info("Listing files")
+		d, _, err := listPaths(w.sources, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing files: %w", err)
+		}
+		w.root = w.newRoot(d)
+		for _, n := range w.root.dirs {
+			w.watchTree(n)
+		}
+		for path := range w.root.files {
+			w.watchDir(filepath.Dir(path))
+		}
+	} else {
+		paths := make([]string, 0, len(changed))
+		for path := range changed {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		opts.Log.Infof("Updating %d paths changed", len(paths))
+		for _, path := range paths {
+			w.apply(path, changed[path], &opts)
+		}
+	}
+
+	// Hash and store the files that changed
+	fileList := make([]*pkgFiles.File, 0, pkg.SliceSmallCapacity)
+	w.root.walk(func(f *pkgFiles.File) {
+		if f.Hash == nil {
+			fileList = append(fileList, f)
+		}
+	})
+	failed, err := w.ingest(fileList, report, &opts)
+	if err != nil {
+		return nil, err
+	}
+	for f := range failed {
+		w.remove(f.RealPath)
+	}
+	report.Files, report.Bytes = 0, 0
+	w.root.walk(func(f *pkgFiles.File) {
+		report.Files++
+		report.Bytes += f.Size
+	})
+
+	// Write snapshot
+	opts.Log.Info("Saving snapshot")
+	d := w.root.toDir(w.sources)
+	if err := snapshots.Write(id.Path(w.repoPath), &snapshots.Snapshot{
+		Version:    internal.Version,
+		Incomplete: w.ctx.Err() != nil,
+		Dirs:       d.Dirs,
+		Files:      d.Files,
+	}); err != nil {
+		return nil, err
+	}
+	if err := w.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("backup interrupted, incomplete snapshot %s saved: %w", id, err)
+	}
+	return report, nil
+}
+
+// apply updates the node of the path provided, listing it again if it's a directory that was not in the tree
+// or the bool provided is true. If a directory that contains it is not in the tree, that directory is updated instead.
+// Paths that are not inside any of the sources are ignored.
+func (w *watcher) apply(path string, relist bool, opts *pkg.Options) {
+	parent, key := w.find(path)
+	if parent == nil {
+		return
+	}
+	if parent == w.root {
+		path = key
+	} else {
+		path = filepath.Join(parent.path, key)
+	}
+	if _, isDir := parent.dirs[key]; isDir && !relist {
+		return
+	}
+
+	remove := func() {
+		delete(parent.dirs, key)
+		delete(parent.files, key)
+	}
+	defer parent.updateInfo()
+	if omitted(filepath.Base(path), opts) {
+		remove()
+		return
+	}
+
+	// The sources are followed if they are links, like when they are listed
+	stat, err := os.Lstat(path)
+	if parent == w.root {
+		stat, err = os.Stat(path)
+	}
+	if err != nil {
+		remove()
+		if !os.IsNotExist(err) {
+			opts.Log.Errorf("cannot get information of \"%s\": %s", path, err)
+			opts.OnSkip(path, err.Error())
+		}
+		return
+	}
+
+	switch {
+	case stat.IsDir():
+		d, _, err := pkgFiles.NewDir(path, opts)
+		if err != nil {
+			remove()
+			opts.Log.Error(err.Error())
+			opts.OnSkip(path, err.Error())
+			return
+		}
+		n := newNode(d, path)
+		remove()
+		parent.dirs[key] = n
+		w.watchTree(n)
+	case stat.Mode().IsRegular():
+		f, err := pkgFiles.NewFile(path)
+		if err != nil {
+			remove()
+			opts.Log.Error(err.Error())
+			opts.OnSkip(path, err.Error())
+			return
+		}
+		remove()
+		parent.files[key] = f
+	default:
+		remove()
+		opts.OnSkip(path, "unsupported file type")
+	}
+}
+
+// find returns the node that must contain the path provided and its key in that node, or nil if the path
+// is not inside any of the sources. If a directory between them is not in the tree, it returns that directory
+// and its parent instead, so it's listed completely.
+func (w *watcher) find(path string) (*node, string) {
+	for _, src := range w.sources {
+		if path == src {
+			return w.root, src
+		}
+		if !strings.HasPrefix(path, src+string(filepath.Separator)) {
+			continue
+		}
+
+		n := w.root.dirs[src]
+		if n == nil {
+			return w.root, src
+		}
+		parts := strings.Split(path[len(src)+1:], string(filepath.Separator))
+		for _, part := range parts[:len(parts)-1] {
+			child := n.dirs[part]
+			if child == nil {
+				return n, part
+			}
+			n = child
+		}
+		return n, parts[len(parts)-1]
+	}
+	return nil, ""
+}
+
+// omitted returns whether the files with the name provided are omitted according to the options provided.
+func omitted(name string, opts *pkg.Options) bool {
+	return opts.OmitHidden && utils.IsHidden(name) || utils.IsExcluded(name, opts.Exclude)
+}
+
+// remove removes the file or directory of the path provided from the tree.
+func (w *watcher) remove(path string) {
+	parent, key := w.find(path)
+	if parent == nil {
+		return
+	}
+	if parent == w.root && key != path || parent != w.root && filepath.Join(parent.path, key) != path {
+		return // Not in the tree
+	}
+	delete(parent.dirs, key)
+	delete(parent.files, key)
+}
+
+// watchTree adds the directories of the node provided to the file system watcher.
+func (w *watcher) watchTree(n *node) {
+	w.watchDir(n.path)
+	for _, child := range n.dirs {
+		w.watchTree(child)
+	}
+}
+
+// watchDir adds the directory of the path provided to the file system watcher, logging the errors.
+func (w *watcher) watchDir(path string) {
+	if err := w.fsw.Add(path); err != nil {
+		w.opts.Log.Errorf("cannot watch \"%s\": %s", path, err)
+	}
+}
+
+// newRoot returns the root node of the tree of the files.Dir provided, that must be the result
+// of listing the sources of the watcher. Its children are identified by their source path.
+func (w *watcher) newRoot(d pkgFiles.Dir) *node {
+	root := &node{
+		dirs:  make(map[string]*node, len(d.Dirs)),
+		files: make(map[string]*pkgFiles.File, len(d.Files)),
+	}
+	dirs, files := d.Dirs, d.Files
+	for _, src := range w.sources {
+		name := filepath.Base(src)
+		if len(dirs) != 0 && dirs[0].Name == name {
+			if stat, err := os.Stat(src); err == nil && stat.IsDir() {
+				root.dirs[src] = newNode(dirs[0], src)
+				dirs = dirs[1:]
+				continue
+			}
+		}
+		if len(files) != 0 && files[0].Name == name {
+			root.files[src] = files[0]
+			files = files[1:]
+		}
+	}
+	return root
+}
+
+// newNode returns the node of the files.Dir provided, that is in the path provided.
+func newNode(d pkgFiles.Dir, path string) *node {
+	n := &node{
+		name:    d.Name,
+		path:    path,
+		mode:    d.Mode,
+		modTime: d.ModTime,
+		dirs:    make(map[string]*node, len(d.Dirs)),
+		files:   make(map[string]*pkgFiles.File, len(d.Files)),
+	}
+	for _, child := range d.Dirs {
+		n.dirs[child.Name] = newNode(child, filepath.Join(path, child.Name))
+	}
+	for _, f := range d.Files {
+		n.files[f.Name] = f
+	}
+	return n
+}
+
+// updateInfo reads again the permissions and the modification time of the directory of the node provided,
+// that change when its entries do. The root node is not a directory.
+func (n *node) updateInfo() {
+	if n.path == "" {
+		return
+	}
+	if stat, err := os.Stat(n.path); err == nil {
+		modTime := stat.ModTime()
+		n.mode, n.modTime = stat.Mode().Perm(), &modTime
+	}
+}
+
+// walk calls the function provided for each file of the tree of the node provided.
+func (n *node) walk(fn func(f *pkgFiles.File)) {
+	for _, f := range n.files {
+		fn(f)
+	}
+	for _, child := range n.dirs {
+		child.walk(fn)
+	}
+}
+
+// toDir returns the files.Dir of the tree of the root node provided, with its children in the order of the sources
+// provided and the rest of the files and directories sorted by name.
+func (n *node) toDir(sources []string) pkgFiles.Dir {
+	d := pkgFiles.Dir{
+		Dirs:  make([]pkgFiles.Dir, 0, len(n.dirs)),
+		Files: make([]*pkgFiles.File, 0, len(n.files)),
+	}
+	for _, src := range sources {
+		if child, ok := n.dirs[src]; ok {
+			d.Dirs = append(d.Dirs, child.dir())
+		}
+		if f, ok := n.files[src]; ok {
+			d.Files = append(d.Files, f)
+		}
+	}
+	return d
+}
+
+// dir returns the files.Dir of the tree of the node provided, with its files and directories sorted by name.
+func (n *node) dir() pkgFiles.Dir {
+	d := pkgFiles.Dir{
+		Name:    n.name,
+		Mode:    n.mode,
+		ModTime: n.modTime,
+		Dirs:    make([]pkgFiles.Dir, 0, len(n.dirs)),
+		Files:   make([]*pkgFiles.File, 0, len(n.files)),
+	}
+	for _, name := range sortedKeys(n.dirs) {
+		d.Dirs = append(d.Dirs, n.dirs[name].dir())
+	}
+	fileNames := make([]string, 0, len(n.files))
+	for name := range n.files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	for _, name := range fileNames {
+		d.Files = append(d.Files, n.files[name])
+	}
+	return d
+}
+
+// sortedKeys returns the keys of the map provided sorted.
+func sortedKeys(m map[string]*node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
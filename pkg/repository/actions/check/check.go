@@ -2,81 +2,234 @@ package check
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
 	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
 	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
-	"github.com/Miguel-Dorta/gkup/pkg/utils"
 	"hash"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"sync"
 )
 
+// ErrIntegrity is the error returned by Check when some of the files fail the check.
+var ErrIntegrity = errors.New("integrity errors found")
+
+// ErrSizeMismatch and ErrHashMismatch are wrapped by the errors of the files whose size or hash
+// doesn't match with the ones contained in their names.
 var (
-	bufferSize int
-	jsonOutput bool
-	statusWriter, errorWriter io.Writer
+	ErrSizeMismatch = errors.New("sizes don't match")
+	ErrHashMismatch = errors.New("hashes don't match")
 )
 
-func Check(path string, bufSize int, json bool, writeStatus, writeErrors io.Writer) error {
-	if bufSize < 512 {
-		bufSize = 512
+// Report represents the result of checking a repository.
+type Report struct {
+	Files    int       // Files checked
+	Failures []Failure // Files that failed the check
+}
+
+// Failure represents a file of the repository that failed the check.
+type Failure struct {
+	Path string
+	Err  error
+}
+
+// Check checks the integrity of all the files stored in the repository of the path provided.
+// It writes its progress in writeStatus and the errors found in writeErrors in an human-readable way
+// or in JSON depending of the bool provided. If any file fails the check, it returns an error wrapping ErrIntegrity.
+// It stops and returns the error of the context provided when it's cancelled.
+func Check(ctx context.Context, path string, opts *pkg.Options, json bool, writeStatus, writeErrors io.Writer) error {
+	opts = opts.Normalize()
+
+	// Get all files
+	safeFileList, hashAlgorithm, err := getRepoFiles(path)
+	if err != nil {
+		return err
+	}
+
+	// Do concurrent check
+	var renderer progress.Renderer
+	if json {
+		renderer = progress.NewNDJSONRenderer(writeStatus)
+	} else {
+		renderer = progress.NewTextRenderer(writeStatus)
+	}
+	tracker := newTracker(safeFileList, renderer)
+	tracker.Start(progress.DefaultInterval)
+	errsFound := 0
+	var mutex sync.Mutex
+	checkFiles(ctx, safeFileList, hashAlgorithm, opts, tracker, func(_ string, err error) {
+		mutex.Lock()
+		errsFound++
+		printError(err, json, writeErrors)
+		mutex.Unlock()
+	})
+	tracker.Finish()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if errsFound != 0 {
+		return fmt.Errorf("%w: %d files failed the check", ErrIntegrity, errsFound)
+	}
+	return nil
+}
+
+// FindFailures checks the integrity of all the files stored in the repository of the path provided
+// and returns a report with the ones that failed the check, sorted by path.
+// It reports its progress to the renderer of the options provided.
+// It stops and returns the error of the context provided when it's cancelled.
+func FindFailures(ctx context.Context, path string, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	safeFileList, hashAlgorithm, err := getRepoFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Files:    safeFileList.GetLenUnsafe(),
+		Failures: make([]Failure, 0, 10),
+	}
+	var mutex sync.Mutex
+	tracker := newTracker(safeFileList, opts.Progress)
+	tracker.Start(progress.DefaultInterval)
+	checkFiles(ctx, safeFileList, hashAlgorithm, opts, tracker, func(path string, err error) {
+		mutex.Lock()
+		report.Failures = append(report.Failures, Failure{Path: path, Err: err})
+		mutex.Unlock()
+	})
+	tracker.Finish()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Failures, func(i, j int) bool {
+		return report.Failures[i].Path < report.Failures[j].Path
+	})
+	return report, nil
+}
+
+// FindCorrupted checks the integrity of all the files stored in the repository of the path provided
+// and returns the paths of the ones that are corrupted, that means, the ones whose size or hash doesn't
+// match with the ones contained in their names, and the ones that cannot be checked because of other errors
+// (like permission or I/O errors), that are not considered corrupted. Both are sorted by path.
+// Files whose name doesn't follow the repository format are ignored.
+// It stops and returns the error of the context provided when it's cancelled.
+func FindCorrupted(ctx context.Context, path string, opts *pkg.Options) ([]string, []Failure, error) {
+	opts = opts.Normalize()
+	safeFileList, hashAlgorithm, err := getRepoFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	corrupted := make([]string, 0, 10)
+	failures := make([]Failure, 0, 10)
+	var mutex sync.Mutex
+	checkFiles(ctx, safeFileList, hashAlgorithm, opts, newTracker(safeFileList, nil), func(path string, err error) {
+		if _, _, err := files.GetDataFromName(filepath.Base(path)); err != nil {
+			return
+		}
+		mutex.Lock()
+		if errors.Is(err, ErrSizeMismatch) || errors.Is(err, ErrHashMismatch) {
+			corrupted = append(corrupted, path)
+		} else {
+			failures = append(failures, Failure{Path: path, Err: err})
+		}
+		mutex.Unlock()
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
-	bufferSize = bufSize
-	jsonOutput = json
-	statusWriter = writeStatus
-	errorWriter = writeErrors
 
-	// Get settings (will be used later)
+	sort.Strings(corrupted)
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Path < failures[j].Path
+	})
+	return corrupted, failures, nil
+}
+
+// getRepoFiles returns a list with all the files stored in the repository of the path provided
+// and the hash algorithm that the repository uses.
+func getRepoFiles(path string) (*threadSafe.StringList, string, error) {
+	// Get settings
 	sett, err := settings.Read(filepath.Join(path, settings.FileName))
 	if err != nil {
-		return fmt.Errorf("error reading settings: %w", err)
+		return nil, "", fmt.Errorf("error reading settings: %w", err)
+	}
+	if _, err := hasher.NewHash(sett.HashAlgorithm); err != nil {
+		return nil, "", fmt.Errorf("error reading settings: %w", err)
 	}
 
 	// Get all files
-	fileList, err := getAllFiles(path)
+	fileList, err := files.List(path)
 	if err != nil {
-		return fmt.Errorf("error listing repository files: %w", err)
+		return nil, "", fmt.Errorf("error listing repository files: %w", err)
+	}
+	return threadSafe.NewStringList(fileList), sett.HashAlgorithm, nil
+}
+
+// newTracker returns a progress.Tracker for checking the files of the list provided that will send its events
+// to the renderer provided.
+func newTracker(safeFileList *threadSafe.StringList, r progress.Renderer) *progress.Tracker {
+	var totalSize int64
+	for _, path := range safeFileList.GetList() {
+		totalSize += getSize(path)
 	}
-	safeFileList := threadSafe.NewStringList(fileList)
 
-	// Do concurrent check
-	quit := make(chan bool)
-	go printStatusAsync(safeFileList, quit)
+	tracker := progress.NewTracker("check", r)
+	tracker.SetTotal(int64(safeFileList.GetLenUnsafe()), totalSize)
+	return tracker
+}
+
+// getSize returns the size of the file of the path provided according to its name, or 0 if its name is invalid.
+func getSize(path string) int64 {
+	_, size, err := files.GetDataFromName(filepath.Base(path))
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// checkFiles checks concurrently the files of the list provided, calling onError for every one
+// that fails the check and reporting the progress to the tracker provided, until the context provided is cancelled.
+// It uses as many workers as threads the normalized options provided have.
+func checkFiles(ctx context.Context, safeFileList *threadSafe.StringList, hashAlgorithm string, opts *pkg.Options, tracker *progress.Tracker, onError func(path string, err error)) {
 	wg := &sync.WaitGroup{}
-	for i:=0; i<runtime.NumCPU(); i++ {
+	for i:=0; i<opts.NumberOfThreads; i++ {
 		wg.Add(1)
 		go func() {
-			checkFilesWorker(safeFileList, sett.HashAlgorithm)
+			checkFilesWorker(ctx, safeFileList, hashAlgorithm, opts.BufferSize, tracker, onError)
 			wg.Done()
 		}()
 	}
 	wg.Wait()
-	quit <- true
-
-	return nil
 }
 
-func checkFilesWorker(safeFileList *threadSafe.StringList, hashAlgorithm string) {
-	buf := make([]byte, bufferSize)
-	h, err := getHash(hashAlgorithm)
+func checkFilesWorker(ctx context.Context, safeFileList *threadSafe.StringList, hashAlgorithm string, bufSize int, tracker *progress.Tracker, onError func(path string, err error)) {
+	buf := make([]byte, bufSize)
+	h, err := hasher.NewHash(hashAlgorithm)
 	if err != nil {
-		printError(err)
+		onError("", err)
 		return
 	}
 
-	for {
+	for ctx.Err() == nil {
 		f := safeFileList.Next()
 		if f == nil {
 			break
 		}
-		if err := checkFile(*f, h, buf); err != nil {
-			printError(err)
+		tracker.SetCurrentFile(*f)
+		err := checkFile(*f, h, buf)
+		tracker.Add(1, getSize(*f))
+		if err != nil {
+			onError(*f, err)
 			continue
 		}
 	}
@@ -99,7 +252,7 @@ func checkFile(path string, h hash.Hash, buf []byte) error {
 		return fmt.Errorf("cannot access file info: %w", err)
 	}
 	if stat.Size() != expectedSize {
-		return fmt.Errorf("sizes don't match in file %s", path)
+		return fmt.Errorf("%w in file %s", ErrSizeMismatch, path)
 	}
 
 	// Check hash
@@ -108,39 +261,8 @@ func checkFile(path string, h hash.Hash, buf []byte) error {
 		return fmt.Errorf("error hashing file: %w", err)
 	}
 	if !bytes.Equal(actualHash, expectedHash) {
-		return fmt.Errorf("hashes don't match in file %s", path)
+		return fmt.Errorf("%w in file %s", ErrHashMismatch, path)
 	}
 
 	return nil
 }
-
-func getAllFiles(path string) ([]string, error) {
-	result := make([]string, 0, 10000)
-
-	filesFolderPath := filepath.Join(path, repository.FilesFolderName)
-	for i:=0; i<=0xff; i++ {
-		dirPath := filepath.Join(filesFolderPath, fmt.Sprintf("%02x", i))
-
-		// List dir
-		fList, err := utils.ListDir(dirPath)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				continue
-			}
-			return nil, &os.PathError{
-				Op:   "getAllRepoFiles",
-				Path: dirPath,
-				Err:  err,
-			}
-		}
-
-		// Add files to list
-		for _, f := range fList {
-			if !f.Mode().IsRegular() {
-				continue
-			}
-			result = append(result, filepath.Join(dirPath, f.Name()))
-		}
-	}
-	return result, nil
-}
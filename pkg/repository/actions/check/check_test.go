@@ -2,19 +2,17 @@ package check_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
 	"regexp"
 	"strconv"
 	"testing"
 )
 
-type statusJSON struct {
-	Type      string `json:"type"`
-	Processed int    `json:"processed"`
-	Total     int    `json:"total"`
-}
-
 type errorJSON struct {
 	Type string `json:"type"`
 	Err  string `json:"error"`
@@ -30,8 +28,8 @@ var errs = map[string]bool{
 
 func TestCheck(t *testing.T) {
 	var statusWriter, errorWriter = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
-	if err := check.Check("testdata", 128*1024, false, statusWriter, errorWriter); err != nil {
-		t.Fatalf("error checking files with JSON==false: %s", err)
+	if err := check.Check(context.Background(), "testdata", &pkg.Options{BufferSize: 128 * 1024}, false, statusWriter, errorWriter); !errors.Is(err, check.ErrIntegrity) {
+		t.Fatalf("unexpected error checking files with JSON==false\n-> Expected: %s\n-> Found: %v", check.ErrIntegrity, err)
 	}
 	checkStatusTXT(statusWriter.Bytes(), t)
 	checkErrorTXT(errorWriter.Bytes(), t)
@@ -42,37 +40,58 @@ func TestCheck(t *testing.T) {
 		errs[k] = false
 	}
 
-	if err := check.Check("testdata", 128*1024, true, statusWriter, errorWriter); err != nil {
-		t.Fatalf("error checking files with JSON==true: %s", err)
+	if err := check.Check(context.Background(), "testdata", &pkg.Options{BufferSize: 128 * 1024}, true, statusWriter, errorWriter); !errors.Is(err, check.ErrIntegrity) {
+		t.Fatalf("unexpected error checking files with JSON==true\n-> Expected: %s\n-> Found: %v", check.ErrIntegrity, err)
 	}
 	checkStatusJSON(statusWriter.Bytes(), t)
 	checkErrorJSON(errorWriter.Bytes(), t)
 }
 
+func TestFindFailures(t *testing.T) {
+	report, err := check.FindFailures(context.Background(), "testdata", &pkg.Options{BufferSize: 128 * 1024})
+	if err != nil {
+		t.Fatalf("error finding failures: %s", err)
+	}
+	if report.Files <= len(errs) {
+		t.Errorf("unexpected number of files checked: %d", report.Files)
+	}
+	if len(report.Failures) != len(errs) {
+		t.Fatalf("unexpected number of failures\n-> Expected: %d\n-> Found: %d", len(errs), len(report.Failures))
+	}
+	for i, f := range report.Failures {
+		if _, exists := errs[f.Err.Error()]; !exists {
+			t.Errorf("unexpected failure: %s", f.Err)
+		}
+		if i != 0 && report.Failures[i-1].Path > f.Path {
+			t.Errorf("failures not sorted by path: %s > %s", report.Failures[i-1].Path, f.Path)
+		}
+	}
+}
+
 func checkStatusJSON(status []byte, t *testing.T) {
-	parts := bytes.Split(status, []byte{0})
-	for _, part := range parts {
-		if len(part) == 0 || string(part) == "\n" {
+	lines := bytes.Split(status, []byte{'\n'})
+	for _, line := range lines {
+		if len(line) == 0 {
 			continue
 		}
 
-		var stat statusJSON
-		if err := json.Unmarshal(part, &stat); err != nil {
-			t.Errorf("cannot unmarshal status msg \"%s\": %s", string(part), err)
+		var e progress.Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Errorf("cannot unmarshal status msg \"%s\": %s", string(line), err)
 			continue
 		}
-		if stat.Type != "status" {
-			t.Errorf("incorrect type in status: %s", stat.Type)
+		if e.Operation != "check" {
+			t.Errorf("incorrect operation in status: %s", e.Operation)
 			continue
 		}
-		if stat.Processed < 0 || stat.Processed > stat.Total {
-			t.Errorf("incorrect processed/total in status json: %d - %d", stat.Processed, stat.Total)
+		if e.Files < 0 || e.Files > e.TotalFiles || e.Bytes > e.TotalBytes {
+			t.Errorf("incorrect processed/total in status json: %+v", e)
 		}
 	}
 }
 
 func checkStatusTXT(status []byte, t *testing.T) {
-	statusTxtRegex := regexp.MustCompile("^Processed files: (\\d+) of (\\d+)[\\n]?$")
+	statusTxtRegex := regexp.MustCompile("^check: (\\d+) of (\\d+) files, .+ of .+ \\(.+/s\\), ETA .+[\\n]?$")
 	parts := bytes.Split(status, []byte{'\r'})
 	for _, part := range parts {
 		if len(part) == 0 {
@@ -102,15 +121,18 @@ func checkStatusTXT(status []byte, t *testing.T) {
 }
 
 func checkErrorJSON(errors []byte, t *testing.T) {
-	parts := bytes.Split(errors, []byte{0})
-	for _, part := range parts {
-		if len(part) == 0 {
+	if len(errors) != 0 && errors[len(errors)-1] != '\n' {
+		t.Errorf("error output doesn't end with a new line: %q", errors)
+	}
+	lines := bytes.Split(errors, []byte{'\n'})
+	for _, line := range lines {
+		if len(line) == 0 {
 			continue
 		}
 
 		var e errorJSON
-		if err := json.Unmarshal(part, &e); err != nil {
-			t.Errorf("cannot unmarshal error msg \"%s\": %s", string(part), err)
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Errorf("cannot unmarshal error msg %q: %s", line, err)
 			continue
 		}
 		if e.Type != "error" {
@@ -0,0 +1,283 @@
+package copy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// copier contains the state of a copy between two repositories.
+type copier struct {
+	ctx      context.Context
+	from, to string
+	fromHash hash.Hash
+	toHash   hash.Hash
+	rehash   bool // Whether both repositories use different algorithms
+	buf      []byte
+	rehashed map[string][]byte // Hashes in the destination of the objects already rehashed, by name in the origin
+	tracker  *progress.Tracker
+	report   *Report
+}
+
+// Copy copies the snapshots of the repo in the path "from" that match the selectors provided to the repo
+// in the path "to", copying only the objects that are missing in the destination and verifying their hashes.
+// See snapshots.Select for the selectors format.
+// If both repositories use different hash algorithms, it returns an error unless rehash is true, in which case
+// the objects will be rehashed with the algorithm of the destination.
+// The progress of the copy is sent to the progress.Renderer of the options provided, if any.
+// It writes a report of the copy in the writer provided in an human-readable way or in JSON depending
+// of the bool provided.
+// It stops and returns the error of the context provided when it's cancelled. The snapshot that was
+// being copied is not written, but the objects already copied are kept.
+func Copy(ctx context.Context, from, to string, selectors []string, rehash bool, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	opts = opts.Normalize()
+
+	// Read settings
+	fromSett, err := settings.Read(filepath.Join(from, settings.FileName))
+	if err != nil {
+		return fmt.Errorf("error reading settings of origin: %w", err)
+	}
+	toSett, err := settings.Read(filepath.Join(to, settings.FileName))
+	if err != nil {
+		return fmt.Errorf("error reading settings of destination: %w", err)
+	}
+
+	c := &copier{
+		ctx:      ctx,
+		from:     from,
+		to:       to,
+		buf:      make([]byte, opts.BufferSize),
+		rehashed: make(map[string][]byte, 1000),
+		report: &Report{
+			Copied:  make([]string, 0, 10),
+			Skipped: make([]string, 0, 10),
+		},
+	}
+	if c.fromHash, err = hasher.NewHash(fromSett.HashAlgorithm); err != nil {
+		return err
+	}
+	if c.toHash, err = hasher.NewHash(toSett.HashAlgorithm); err != nil {
+		return err
+	}
+	if !strings.EqualFold(fromSett.HashAlgorithm, toSett.HashAlgorithm) {
+		if !rehash {
+			return fmt.Errorf("hash algorithms don't match (%s in origin, %s in destination)", fromSett.HashAlgorithm, toSett.HashAlgorithm)
+		}
+		c.rehash = true
+	}
+
+	// Get snapshots
+	ids, err := snapshots.List(from)
+	if err != nil {
+		return fmt.Errorf("cannot get snapshots: %w", err)
+	}
+	if ids, err = snapshots.Select(ids, selectors); err != nil {
+		return err
+	}
+
+	// Copy
+	if c.tracker, err = newTracker(from, to, ids, opts.Progress); err != nil {
+		return err
+	}
+	c.tracker.Start(progress.DefaultInterval)
+	for _, id := range ids {
+		if err := c.copySnapshot(id); err != nil {
+			c.tracker.Finish()
+			return fmt.Errorf("error copying snapshot %s: %w", id, err)
+		}
+	}
+	c.tracker.Finish()
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(c.report)
+	} else {
+		output = getTXT(c.report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	return nil
+}
+
+// newTracker returns a progress.Tracker for copying the files of the snapshots provided that don't exist
+// in the destination yet, that will send its events to the renderer provided.
+func newTracker(from, to string, ids []snapshots.ID, r progress.Renderer) (*progress.Tracker, error) {
+	var totalFiles, totalSize int64
+	for _, id := range ids {
+		if _, err := os.Stat(id.Path(to)); err == nil {
+			continue
+		}
+
+		snap, err := snapshots.Read(id.Path(from))
+		if err != nil {
+			return nil, err
+		}
+		_ = snap.Walk(func(_ string, f *pkgFiles.File) error {
+			totalFiles++
+			totalSize += f.Size
+			return nil
+		})
+	}
+
+	tracker := progress.NewTracker("copy", r)
+	tracker.SetTotal(totalFiles, totalSize)
+	return tracker, nil
+}
+
+// copySnapshot copies the snapshot provided and its objects.
+// The snapshot file is written after all its objects are copied.
+func (c *copier) copySnapshot(id snapshots.ID) error {
+	destination := id.Path(c.to)
+	if _, err := os.Stat(destination); err == nil {
+		c.report.Skipped = append(c.report.Skipped, id.String())
+		return nil
+	}
+
+	snap, err := snapshots.Read(id.Path(c.from))
+	if err != nil {
+		return err
+	}
+	if err := snap.Walk(func(path string, f *pkgFiles.File) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		c.tracker.SetCurrentFile(id.String() + "/" + path)
+		defer c.tracker.Add(1, f.Size)
+		return c.copyObject(f)
+	}); err != nil {
+		return err
+	}
+
+	if err := snapshots.Write(destination, snap); err != nil {
+		return err
+	}
+	c.report.Copied = append(c.report.Copied, id.String())
+	return nil
+}
+
+// copyObject copies the object of the file provided to the destination if it doesn't exist there.
+// If the object is rehashed, the hash of the file is updated.
+func (c *copier) copyObject(f *pkgFiles.File) error {
+	name := files.GetObjectName(f)
+
+	// Skip objects already in the destination
+	var collision int
+	if !c.rehash {
+		found, index, err := c.findObject(f)
+		if err != nil {
+			return err
+		}
+		if found {
+			f.Collision = index
+			c.report.ObjectsSkipped++
+			return nil
+		}
+		collision = index
+	} else if newHash, ok := c.rehashed[name]; ok {
+		f.Hash, f.Collision = newHash, 0
+		return nil
+	}
+
+	src, err := os.Open(files.GetObjectPath(c.from, f))
+	if err != nil {
+		return fmt.Errorf("cannot open object %s: %w", name, err)
+	}
+	defer src.Close()
+
+	obj, err := files.NewTempObject(c.to, c.toHash)
+	if err != nil {
+		return err
+	}
+
+	// Copy while hashing with the algorithm of the origin to verify the object
+	c.fromHash.Reset()
+	size, err := io.CopyBuffer(obj, io.TeeReader(utils.NewContextReader(c.ctx, src), c.fromHash), c.buf)
+	if err != nil {
+		_ = obj.Discard()
+		return fmt.Errorf("error copying object %s: %w", name, err)
+	}
+	if size != f.Size {
+		_ = obj.Discard()
+		return fmt.Errorf("sizes don't match in object %s", name)
+	}
+	if !bytes.Equal(c.fromHash.Sum(nil), f.Hash) {
+		_ = obj.Discard()
+		return fmt.Errorf("hashes don't match in object %s", name)
+	}
+
+	// Use the collision index of the destination. Objects rehashed don't have any, since their new hash is different
+	newHash, _, err := obj.CommitCollision(collision)
+	if err != nil {
+		return err
+	}
+	if c.rehash {
+		c.rehashed[name] = newHash
+		f.Hash = newHash
+	}
+	f.Collision = collision
+
+	c.report.ObjectsCopied++
+	c.report.BytesCopied += size
+	return nil
+}
+
+// findObject looks for an object with the same content than the object of the file provided in the destination,
+// that uses the same hash algorithm than the origin. It returns whether it was found and its collision index
+// in the destination, or the first free collision index if it wasn't found.
+// As collision indexes are local to each repository, the contents are compared if there is any collision
+// of the object in any of both repositories.
+func (c *copier) findObject(f *pkgFiles.File) (bool, int, error) {
+	srcPath := files.GetObjectPath(c.from, f)
+	candidate := &pkgFiles.File{Hash: f.Hash, Size: f.Size}
+	collided := f.Collision != 0
+	for _, repoPath := range []string{c.from, c.to} {
+		candidate.Collision = 1
+		if _, err := os.Stat(files.GetObjectPath(repoPath, candidate)); err == nil {
+			collided = true
+		}
+	}
+
+	half := len(c.buf) / 2
+	for candidate.Collision = 0; ; candidate.Collision++ {
+		dstPath := files.GetObjectPath(c.to, candidate)
+		_, err := os.Stat(dstPath)
+		if os.IsNotExist(err) {
+			return false, candidate.Collision, nil
+		}
+		if err != nil {
+			return false, 0, &os.PathError{
+				Op:   "stat object",
+				Path: dstPath,
+				Err:  err,
+			}
+		}
+		if !collided {
+			return true, candidate.Collision, nil
+		}
+
+		equal, err := utils.EqualFiles(srcPath, dstPath, c.buf[:half], c.buf[half:2*half])
+		if err != nil {
+			return false, 0, err
+		}
+		if equal {
+			return true, candidate.Collision, nil
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package copy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/copy"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestCopy")
+	contents    = [][]byte{[]byte("first content"), []byte("second content"), []byte("third content")}
+	ids         = []snapshots.ID{
+		snapshots.NewID("", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		snapshots.NewID("mypc", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		snapshots.NewID("mypc", time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+)
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestCopy(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	from := createRepo("from", "sha256", t)
+	to := createRepo("to", "sha256", t)
+	toMD5 := createRepo("to_md5", "md5", t)
+
+	for _, content := range contents {
+		addObject(from, content, t)
+	}
+	writeSnapshot(from, ids[0], contents[0], contents[1], t)
+	writeSnapshot(from, ids[1], contents[1], contents[2], t)
+	writeSnapshot(from, ids[2], contents[2], contents[2], t)
+
+	// Copy by name
+	report := doCopy(from, to, []string{"mypc"}, false, t)
+	if len(report.Copied) != 2 || report.ObjectsCopied != 2 || report.BytesCopied != int64(len(contents[1])+len(contents[2])) {
+		t.Errorf("unexpected report copying mypc: %+v", report)
+	}
+
+	// Copy again
+	report = doCopy(from, to, nil, false, t)
+	if len(report.Copied) != 1 || len(report.Skipped) != 2 || report.ObjectsCopied != 1 || report.ObjectsSkipped != 1 {
+		t.Errorf("unexpected report copying all: %+v", report)
+	}
+	for _, id := range ids {
+		checkSnapshot(to, id, sha256Sum, t)
+	}
+
+	// Copy with different algorithms
+	if err := copy.Copy(context.Background(), from, toMD5, nil, false, nil, true, bytes.NewBuffer(nil)); err == nil {
+		t.Error("not error copying to a repository with a different algorithm")
+	}
+	report = doCopy(from, toMD5, []string{ids[1].String()}, true, t)
+	if len(report.Copied) != 1 || report.ObjectsCopied != 2 {
+		t.Errorf("unexpected report rehashing: %+v", report)
+	}
+	checkSnapshot(toMD5, ids[1], md5Sum, t)
+
+	// Not existing selector
+	if err := copy.Copy(context.Background(), from, to, []string{"not_found"}, false, nil, true, bytes.NewBuffer(nil)); err == nil {
+		t.Error("not error copying not existing snapshot")
+	}
+}
+
+func TestCopy_Collision(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	from := createRepo("from", "sha256", t)
+	toEqual := createRepo("to_equal", "sha256", t)
+	toOther := createRepo("to_other", "sha256", t)
+
+	// The object of the snapshot has collision index 1 in the origin
+	content, collided := contents[0], []byte("FIRST content")
+	f := &pkgFiles.File{Name: "a", Size: int64(len(content)), Hash: sha256Sum(content), Collision: 1}
+	writeObject(from, f, 0, collided, t)
+	writeObject(from, f, 1, content, t)
+	if err := snapshots.Write(ids[1].Path(from), &snapshots.Snapshot{Files: []*pkgFiles.File{f}}); err != nil {
+		t.Fatalf("error writing snapshot: %s", err)
+	}
+
+	// Same content with other collision index in the destination
+	writeObject(toEqual, f, 0, content, t)
+	report := doCopy(from, toEqual, nil, false, t)
+	if report.ObjectsCopied != 0 || report.ObjectsSkipped != 1 {
+		t.Errorf("unexpected report copying to a repository with the object: %+v", report)
+	}
+	checkCollision(toEqual, 0, content, t)
+
+	// Other contents with the same collision index in the destination
+	writeObject(toOther, f, 0, collided, t)
+	writeObject(toOther, f, 1, []byte("First Content"), t)
+	report = doCopy(from, toOther, nil, false, t)
+	if report.ObjectsCopied != 1 || report.ObjectsSkipped != 0 {
+		t.Errorf("unexpected report copying to a repository with collisions: %+v", report)
+	}
+	checkCollision(toOther, 2, content, t)
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+// writeObject writes the content provided as the object of the file provided with the collision index provided.
+func writeObject(repoPath string, f *pkgFiles.File, collision int, content []byte, t *testing.T) {
+	obj := &pkgFiles.File{Hash: f.Hash, Size: f.Size, Collision: collision}
+	path := files.GetObjectPath(repoPath, obj)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatalf("error creating directory of object %s: %s", path, err)
+	}
+	if err := ioutil.WriteFile(path, content, 0666); err != nil {
+		t.Fatalf("error writing object %s: %s", path, err)
+	}
+}
+
+// checkCollision checks that the file of the snapshot copied has the collision index provided
+// and that its object has the content provided.
+func checkCollision(repoPath string, collision int, content []byte, t *testing.T) {
+	snap, err := snapshots.Read(ids[1].Path(repoPath))
+	if err != nil {
+		t.Fatalf("error reading snapshot copied: %s", err)
+	}
+	f := snap.Files[0]
+	if f.Collision != collision {
+		t.Errorf("unexpected collision index in %s: %d", repoPath, f.Collision)
+	}
+	data, err := ioutil.ReadFile(files.GetObjectPath(repoPath, f))
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("unexpected object in %s: %q, %v", repoPath, data, err)
+	}
+}
+
+func sha256Sum(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+func md5Sum(data []byte) []byte {
+	h := md5.Sum(data)
+	return h[:]
+}
+
+func createRepo(name, hashAlgorithm string, t *testing.T) string {
+	path := filepath.Join(testingPath, name)
+	if err := create.Create(path, hashAlgorithm); err != nil {
+		t.Fatalf("error creating repo %s: %s", name, err)
+	}
+	return path
+}
+
+func addObject(repoPath string, content []byte, t *testing.T) {
+	obj, err := files.NewTempObject(repoPath, sha256.New())
+	if err != nil {
+		t.Fatalf("error creating object: %s", err)
+	}
+	if _, err := obj.Write(content); err != nil {
+		t.Fatalf("error writing object: %s", err)
+	}
+	if _, _, err := obj.Commit(); err != nil {
+		t.Fatalf("error committing object: %s", err)
+	}
+}
+
+func writeSnapshot(repoPath string, id snapshots.ID, content1, content2 []byte, t *testing.T) {
+	if err := snapshots.Write(id.Path(repoPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{{Name: "a", Size: int64(len(content1)), Hash: sha256Sum(content1)}},
+		Dirs: []pkgFiles.Dir{{
+			Name:  "dir",
+			Files: []*pkgFiles.File{{Name: "b", Size: int64(len(content2)), Hash: sha256Sum(content2)}},
+		}},
+	}); err != nil {
+		t.Fatalf("error writing snapshot: %s", err)
+	}
+}
+
+func doCopy(from, to string, selectors []string, rehash bool, t *testing.T) copy.Report {
+	output, status := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := copy.Copy(context.Background(), from, to, selectors, rehash, &pkg.Options{Progress: progress.NewNDJSONRenderer(status)}, true, output); err != nil {
+		t.Fatalf("error copying from %s to %s: %s", from, to, err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(status.Bytes()), []byte{'\n'})
+	var last progress.Event
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil {
+		t.Fatalf("cannot unmarshal progress event %s: %s", lines[len(lines)-1], err)
+	}
+	if last.Type != progress.EventFinish || last.Files != last.TotalFiles || last.Bytes != last.TotalBytes {
+		t.Errorf("unexpected finish event: %+v", last)
+	}
+
+	var report copy.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	return report
+}
+
+// checkSnapshot checks that the snapshot exists in the repo and that all its objects exist and have the hash expected.
+func checkSnapshot(repoPath string, id snapshots.ID, sum func([]byte) []byte, t *testing.T) {
+	snap, err := snapshots.Read(id.Path(repoPath))
+	if err != nil {
+		t.Errorf("error reading snapshot %s: %s", id, err)
+		return
+	}
+
+	_ = snap.Walk(func(path string, f *pkgFiles.File) error {
+		for _, content := range contents {
+			if int64(len(content)) == f.Size && bytes.Equal(sum(content), f.Hash) {
+				if _, err := os.Stat(files.GetPath(repoPath, f.Hash, f.Size)); err != nil {
+					t.Errorf("object of %s in snapshot %s not found: %s", path, id, err)
+				}
+				return nil
+			}
+		}
+		t.Errorf("unexpected hash of %s in snapshot %s: %x", path, id, f.Hash)
+		return nil
+	})
+}
@@ -0,0 +1,37 @@
+package copy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of a copy between repositories.
+type Report struct {
+	Copied         []string `json:"copied"`
+	Skipped        []string `json:"skipped"`
+	ObjectsCopied  int      `json:"objects_copied"`
+	ObjectsSkipped int      `json:"objects_skipped"`
+	BytesCopied    int64    `json:"bytes_copied"`
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	for _, id := range report.Copied {
+		_, _ = fmt.Fprintf(buf, "- copied %s\n", id)
+	}
+	for _, id := range report.Skipped {
+		_, _ = fmt.Fprintf(buf, "- skipped %s (already in destination)\n", id)
+	}
+	_, _ = fmt.Fprintf(buf, "Snapshots copied: %d (skipped: %d)\nObjects copied: %d (%d bytes, skipped: %d)\n",
+		len(report.Copied), len(report.Skipped), report.ObjectsCopied, report.BytesCopied, report.ObjectsSkipped)
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
 	"github.com/Miguel-Dorta/gkup/pkg/repository"
 	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
 	"github.com/Miguel-Dorta/gkup/pkg/utils"
@@ -12,6 +13,11 @@ import (
 )
 
 func Create(path, hashAlgorithm string) error {
+	// Check if the hash algorithm is supported
+	if _, err := hasher.GetAlgorithm(hashAlgorithm); err != nil {
+		return err
+	}
+
 	// Get path stat
 	stat, err := os.Stat(path)
 	if err != nil {
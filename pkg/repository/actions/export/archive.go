@@ -0,0 +1,105 @@
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"io"
+	"os"
+	"time"
+)
+
+// fileMode and dirMode are the permissions that the entries of the archives will have
+// when the snapshot doesn't record them.
+const (
+	fileMode = pkg.DefaultFilePerm
+	dirMode  = pkg.DefaultDirPerm
+)
+
+// tarWriter is an archiveWriter for tar archives, optionally compressed with gzip.
+type tarWriter struct {
+	tw *tar.Writer
+	gw *gzip.Writer
+}
+
+func newTarWriter(w io.Writer, compress bool) *tarWriter {
+	t := &tarWriter{}
+	if compress {
+		t.gw = gzip.NewWriter(w)
+		w = t.gw
+	}
+	t.tw = tar.NewWriter(w)
+	return t
+}
+
+func (t *tarWriter) writeDir(path string, mode os.FileMode, modTime time.Time) error {
+	return t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     path + "/",
+		Mode:     int64(mode),
+		ModTime:  modTime,
+	})
+}
+
+func (t *tarWriter) writeFile(path string, size int64, mode os.FileMode, modTime time.Time, r io.Reader, buf []byte) error {
+	if err := t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path,
+		Size:     size,
+		Mode:     int64(mode),
+		ModTime:  modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := io.CopyBuffer(t.tw, r, buf)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	if t.gw != nil {
+		return t.gw.Close()
+	}
+	return nil
+}
+
+// zipWriter is an archiveWriter for zip archives.
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{zw: zip.NewWriter(w)}
+}
+
+func (z *zipWriter) writeDir(path string, mode os.FileMode, modTime time.Time) error {
+	h := &zip.FileHeader{
+		Name:     path + "/",
+		Modified: modTime,
+	}
+	h.SetMode(os.ModeDir | mode)
+	_, err := z.zw.CreateHeader(h)
+	return err
+}
+
+func (z *zipWriter) writeFile(path string, _ int64, mode os.FileMode, modTime time.Time, r io.Reader, buf []byte) error {
+	h := &zip.FileHeader{
+		Name:     path,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+	h.SetMode(mode)
+	w, err := z.zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyBuffer(w, r, buf)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
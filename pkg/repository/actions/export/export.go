@@ -0,0 +1,148 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Supported archive formats
+const (
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+// archiveWriter is the interface that the writers of the different archive formats implement.
+type archiveWriter interface {
+	writeDir(path string, mode os.FileMode, modTime time.Time) error
+	writeFile(path string, size int64, mode os.FileMode, modTime time.Time, r io.Reader, buf []byte) error
+	Close() error
+}
+
+// Export takes the repo path, finds the snapshot that matches the selector provided (see snapshots.Find),
+// and writes its tree in the writer provided as an archive of the format provided, reading the files
+// directly from the repo.
+// Entries keep the permissions and modification times recorded in the snapshot. The ones without them
+// (snapshots of older versions, backups of standard input and directories without an entry in imported
+// archives) will have the default permissions and the snapshot time as modification time.
+// It stops and returns the error of the context provided when it's cancelled, leaving the archive incomplete.
+func Export(ctx context.Context, path, snapshot, format string, opts *pkg.Options, writeTo io.Writer) error {
+	opts = opts.Normalize()
+
+	id, err := snapshots.Find(path, snapshot)
+	if err != nil {
+		return err
+	}
+	snap, err := snapshots.Read(id.Path(path))
+	if err != nil {
+		return err
+	}
+
+	var w archiveWriter
+	switch format {
+	case FormatTar:
+		w = newTarWriter(writeTo, false)
+	case FormatTarGz:
+		w = newTarWriter(writeTo, true)
+	case FormatZip:
+		w = newZipWriter(writeTo)
+	default:
+		return fmt.Errorf("archive format %s is not supported", format)
+	}
+
+	e := &exporter{
+		ctx:      ctx,
+		repoPath: path,
+		modTime:  id.Time,
+		w:        w,
+		buf:      make([]byte, opts.BufferSize),
+	}
+	if err := e.exportDir(pkgFiles.Dir{Dirs: snap.Dirs, Files: snap.Files}, ""); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %w", err)
+	}
+	return nil
+}
+
+// exporter contains the state of an export.
+type exporter struct {
+	ctx      context.Context
+	repoPath string
+	modTime  time.Time
+	w        archiveWriter
+	buf      []byte
+}
+
+// exportDir writes the directory provided and its children in the archive.
+// The path provided is the path of the directory in the archive.
+func (e *exporter) exportDir(d pkgFiles.Dir, dirPath string) error {
+	if dirPath != "" {
+		if err := e.w.writeDir(dirPath, getMode(d.Mode, dirMode), e.getModTime(d.ModTime)); err != nil {
+			return fmt.Errorf("error writing directory %s: %w", dirPath, err)
+		}
+	}
+
+	for _, f := range d.Files {
+		if err := e.exportFile(f, path.Join(dirPath, f.Name)); err != nil {
+			return err
+		}
+	}
+	for _, child := range d.Dirs {
+		if err := e.exportDir(child, path.Join(dirPath, child.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportFile writes the file provided in the archive, reading it from its object in the repo.
+func (e *exporter) exportFile(f *pkgFiles.File, filePath string) error {
+	objPath := files.GetObjectPath(e.repoPath, f)
+	obj, err := os.Open(objPath)
+	if err != nil {
+		return fmt.Errorf("cannot open object of %s: %w", filePath, err)
+	}
+	defer obj.Close()
+
+	stat, err := obj.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot access object info of %s: %w", filePath, err)
+	}
+	if stat.Size() != f.Size {
+		return fmt.Errorf("sizes don't match in file %s", objPath)
+	}
+
+	r := utils.NewContextReader(e.ctx, io.LimitReader(obj, f.Size))
+	if err := e.w.writeFile(filePath, f.Size, getMode(f.Mode, fileMode), e.getModTime(f.ModTime), r, e.buf); err != nil {
+		return fmt.Errorf("error writing file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// getModTime returns the modification time provided, or the snapshot time if it's unknown.
+func (e *exporter) getModTime(modTime *time.Time) time.Time {
+	if modTime == nil {
+		return e.modTime
+	}
+	return *modTime
+}
+
+// getMode returns the permissions provided, or the default ones provided if they're unknown.
+func getMode(mode, def os.FileMode) os.FileMode {
+	if mode == 0 {
+		return def
+	}
+	return mode.Perm()
+}
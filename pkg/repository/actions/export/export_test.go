@@ -0,0 +1,167 @@
+package export_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/export"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath  = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestExport")
+	snapshotTime = time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)
+	recordedTime = time.Date(2019, 6, 15, 12, 30, 0, 0, time.UTC)
+	expected     = []struct {
+		path    string
+		content []byte
+		mode    os.FileMode
+		modTime time.Time
+	}{
+		{"a", []byte("first content"), 0644, snapshotTime},
+		{"dir/", nil, 0700, recordedTime},
+		{"dir/b", []byte("second content"), 0600, recordedTime},
+		{"dir/c", []byte("first content"), 0644, snapshotTime},
+		{"dir/empty/", nil, 0755, snapshotTime},
+	}
+)
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestExport(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(testingPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	writeSnapshot(t)
+
+	// Tar and tar.gz
+	for _, format := range []string{export.FormatTar, export.FormatTarGz} {
+		output := bytes.NewBuffer(nil)
+		if err := export.Export(context.Background(), testingPath, "mypc", format, nil, output); err != nil {
+			t.Fatalf("error exporting %s: %s", format, err)
+		}
+
+		var r io.Reader = output
+		if format == export.FormatTarGz {
+			gr, err := gzip.NewReader(output)
+			if err != nil {
+				t.Fatalf("error reading gzip: %s", err)
+			}
+			r = gr
+		}
+
+		tr := tar.NewReader(r)
+		for i := 0; ; i++ {
+			h, err := tr.Next()
+			if err == io.EOF {
+				if i != len(expected) {
+					t.Errorf("unexpected number of entries in %s: %d", format, i)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("error reading %s: %s", format, err)
+			}
+			content, _ := ioutil.ReadAll(tr)
+			checkEntry(i, h.Name, os.FileMode(h.Mode), h.ModTime, content, t)
+		}
+	}
+
+	// Zip
+	output := bytes.NewBuffer(nil)
+	if err := export.Export(context.Background(), testingPath, "mypc/2019-12-31_23-59-59", export.FormatZip, nil, output); err != nil {
+		t.Fatalf("error exporting zip: %s", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("error reading zip: %s", err)
+	}
+	if len(zr.File) != len(expected) {
+		t.Errorf("unexpected number of entries in zip: %d", len(zr.File))
+	}
+	for i, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("error opening %s in zip: %s", f.Name, err)
+		}
+		content, _ := ioutil.ReadAll(rc)
+		_ = rc.Close()
+		checkEntry(i, f.Name, f.Mode(), f.Modified, content, t)
+	}
+
+	// Invalid cases
+	if err := export.Export(context.Background(), testingPath, "mypc", "rar", nil, ioutil.Discard); err == nil {
+		t.Error("not error exporting unsupported format")
+	}
+	if err := export.Export(context.Background(), testingPath, "laptop", export.FormatTar, nil, ioutil.Discard); err == nil {
+		t.Error("not error exporting not existing snapshot")
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func getFile(name string, content []byte) *pkgFiles.File {
+	hash := sha256.Sum256(content)
+	path := files.GetPath(testingPath, hash[:], int64(len(content)))
+	_ = ioutil.WriteFile(path, content, 0666)
+
+	return &pkgFiles.File{
+		Name: name,
+		Size: int64(len(content)),
+		Hash: hash[:],
+	}
+}
+
+func writeSnapshot(t *testing.T) {
+	id := snapshots.NewID("mypc", snapshotTime)
+	b := getFile("b", expected[2].content)
+	b.Mode, b.ModTime = expected[2].mode, &expected[2].modTime
+	if err := snapshots.Write(id.Path(testingPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{getFile("a", expected[0].content)},
+		Dirs: []pkgFiles.Dir{{
+			Name:    "dir",
+			Mode:    expected[1].mode,
+			ModTime: &expected[1].modTime,
+			Files:   []*pkgFiles.File{b, getFile("c", expected[3].content)},
+			Dirs:    []pkgFiles.Dir{{Name: "empty"}},
+		}},
+	}); err != nil {
+		t.Fatalf("error writing snapshot: %s", err)
+	}
+}
+
+func checkEntry(i int, name string, mode os.FileMode, modTime time.Time, content []byte, t *testing.T) {
+	if i >= len(expected) {
+		return
+	}
+	if name != expected[i].path {
+		t.Errorf("unexpected entry in position %d: %s", i, name)
+	}
+	if mode.Perm() != expected[i].mode {
+		t.Errorf("unexpected permissions in %s: %s", name, mode)
+	}
+	if !modTime.Equal(expected[i].modTime) {
+		t.Errorf("unexpected modification time in %s: %s", name, modTime)
+	}
+	if !bytes.Equal(content, expected[i].content) {
+		t.Errorf("unexpected content in %s: %s", name, string(content))
+	}
+}
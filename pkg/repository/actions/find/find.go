@@ -0,0 +1,112 @@
+package find
+
+import (
+	"bytes"
+	"fmt"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Filter represents the conditions that the files found must meet.
+// The zero value of each field means that it is not taken into account.
+type Filter struct {
+	Since   time.Time // Snapshots created after (or in) this moment
+	Until   time.Time // Snapshots created before (or in) this moment
+	MinSize int64     // Files of this size or bigger
+	MaxSize int64     // Files of this size or smaller
+	Hash    []byte    // Files whose hash starts with these bytes
+}
+
+// Find takes the repo path and writes in the writer provided the files of the snapshots that match the selectors
+// provided (see snapshots.Select) that match the pattern and the filter provided, in an human-readable way
+// or in JSON depending of the bool provided.
+// The pattern can be a regular expression, that will be matched against the path of the files,
+// or a glob pattern (see path.Match), that will be matched against the path of the files if it contains
+// a '/' or against their names otherwise.
+func Find(path, pattern string, isRegex bool, selectors []string, filter Filter, inJson bool, writeTo io.Writer) error {
+	match, err := getMatchFunc(pattern, isRegex)
+	if err != nil {
+		return err
+	}
+
+	ids, err := snapshots.List(path)
+	if err != nil {
+		return fmt.Errorf("cannot get snapshots: %w", err)
+	}
+	if ids, err = snapshots.Select(ids, selectors); err != nil {
+		return err
+	}
+
+	matches := make([]Match, 0, 100)
+	for _, id := range ids {
+		if (!filter.Since.IsZero() && id.Time.Before(filter.Since)) || (!filter.Until.IsZero() && id.Time.After(filter.Until)) {
+			continue
+		}
+
+		snap, err := snapshots.Read(id.Path(path))
+		if err != nil {
+			return err
+		}
+		_ = snap.Walk(func(filePath string, f *pkgFiles.File) error {
+			if filter.matches(f) && match(filePath) {
+				matches = append(matches, Match{
+					Snapshot: id.String(),
+					Path:     filePath,
+					Size:     f.Size,
+					Time:     id.Time.Unix(),
+					Hash:     fmt.Sprintf("%x", f.Hash),
+				})
+			}
+			return nil
+		})
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(matches)
+	} else {
+		output = getTXT(matches)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write matches to writer provided: %w", err)
+	}
+	return nil
+}
+
+// matches returns whether the file provided meets the conditions of the filter.
+func (filter Filter) matches(f *pkgFiles.File) bool {
+	return (filter.MinSize == 0 || f.Size >= filter.MinSize) &&
+		(filter.MaxSize == 0 || f.Size <= filter.MaxSize) &&
+		bytes.HasPrefix(f.Hash, filter.Hash)
+}
+
+// getMatchFunc returns a function that returns whether a file path matches the pattern provided.
+func getMatchFunc(pattern string, isRegex bool) (func(string) bool, error) {
+	if isRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	matchPath := strings.ContainsRune(pattern, '/')
+	return func(filePath string) bool {
+		if !matchPath {
+			filePath = path.Base(filePath)
+		}
+		matched, _ := path.Match(pattern, filePath)
+		return matched
+	}, nil
+}
@@ -0,0 +1,103 @@
+package find_test
+
+import (
+	"bytes"
+	"encoding/json"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/find"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestFind")
+
+func TestFind(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	writeSnapshot(snapshots.NewID("", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)), 10)
+	writeSnapshot(snapshots.NewID("mypc", time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)), 20)
+	writeSnapshot(snapshots.NewID("mypc", time.Date(2019, 1, 3, 0, 0, 0, 0, time.UTC)), 30)
+
+	cases := []struct {
+		pattern   string
+		isRegex   bool
+		selectors []string
+		filter    find.Filter
+		expected  []string
+	}{
+		{"*.txt", false, nil, find.Filter{}, []string{
+			"2019-01-01_00-00-00:docs/notes.txt",
+			"mypc/2019-01-02_00-00-00:docs/notes.txt",
+			"mypc/2019-01-03_00-00-00:docs/notes.txt",
+		}},
+		{"*/report.*", false, nil, find.Filter{}, []string{
+			"2019-01-01_00-00-00:docs/report.pdf",
+			"mypc/2019-01-02_00-00-00:docs/report.pdf",
+			"mypc/2019-01-03_00-00-00:docs/report.pdf",
+		}},
+		{"report.*", false, []string{"mypc"}, find.Filter{MinSize: 20}, []string{
+			"mypc/2019-01-02_00-00-00:report.pdf",
+			"mypc/2019-01-03_00-00-00:report.pdf",
+			"mypc/2019-01-02_00-00-00:docs/report.pdf",
+			"mypc/2019-01-03_00-00-00:docs/report.pdf",
+		}},
+		{"^docs/.*\\.(pdf|txt)$", true, nil, find.Filter{Since: time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC), MaxSize: 20}, []string{
+			"mypc/2019-01-02_00-00-00:docs/notes.txt",
+			"mypc/2019-01-02_00-00-00:docs/report.pdf",
+			"mypc/2019-01-03_00-00-00:docs/notes.txt",
+		}},
+		{"*", false, nil, find.Filter{Until: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Hash: []byte{10}}, []string{
+			"2019-01-01_00-00-00:report.pdf",
+			"2019-01-01_00-00-00:docs/report.pdf",
+		}},
+	}
+
+	for _, c := range cases {
+		output := bytes.NewBuffer(nil)
+		if err := find.Find(testingPath, c.pattern, c.isRegex, c.selectors, c.filter, true, output); err != nil {
+			t.Errorf("error finding %s: %s", c.pattern, err)
+			continue
+		}
+
+		var matches find.MatchesJSON
+		if err := json.Unmarshal(output.Bytes(), &matches); err != nil {
+			t.Errorf("cannot unmarshal matches %s: %s", output.String(), err)
+			continue
+		}
+		found := make(map[string]bool, len(matches.Matches))
+		for _, m := range matches.Matches {
+			found[m.Snapshot+":"+m.Path] = true
+		}
+		if len(found) != len(c.expected) {
+			t.Errorf("unexpected matches finding %s: %+v", c.pattern, matches.Matches)
+			continue
+		}
+		for _, e := range c.expected {
+			if !found[e] {
+				t.Errorf("%s not found finding %s", e, c.pattern)
+			}
+		}
+	}
+
+	if err := find.Find(testingPath, "[", false, nil, find.Filter{}, true, bytes.NewBuffer(nil)); err == nil {
+		t.Error("not error finding invalid pattern")
+	}
+}
+
+// writeSnapshot writes a snapshot where the size of report.pdf is the size provided,
+// and the first byte of its hash is the size provided.
+func writeSnapshot(id snapshots.ID, reportSize int64) {
+	report := &pkgFiles.File{Name: "report.pdf", Size: reportSize, Hash: []byte{byte(reportSize), 0xff}}
+	_ = snapshots.Write(id.Path(testingPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{report},
+		Dirs: []pkgFiles.Dir{{
+			Name: "docs",
+			Files: []*pkgFiles.File{
+				{Name: "notes.txt", Size: 15, Hash: []byte{15, 0xff}},
+				report,
+			},
+		}},
+	})
+}
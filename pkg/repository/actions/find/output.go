@@ -0,0 +1,40 @@
+package find
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type MatchesJSON struct {
+	Matches []Match `json:"matches"`
+}
+
+// Match represents a file found in a snapshot.
+type Match struct {
+	Snapshot string `json:"snapshot"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Time     int64  `json:"time"` // Unix time of the snapshot
+	Hash     string `json:"hash"`
+}
+
+// getTXT returns a easily-readable representation of the matches provided.
+func getTXT(matches []Match) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	for _, m := range matches {
+		t := time.Unix(m.Time, 0).UTC()
+		Y, M, D := t.Date()
+		h, min, s := t.Clock()
+		_, _ = fmt.Fprintf(buf, "%s: %s (%d bytes, %04d/%02d/%02d %02d:%02d:%02d)\n", m.Snapshot, m.Path, m.Size, Y, M, D, h, min, s)
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the matches provided.
+func getJSON(matches []Match) []byte {
+	data, _ := json.Marshal(MatchesJSON{Matches: matches})
+	return append(data, '\n')
+}
@@ -0,0 +1,215 @@
+package forget
+
+import (
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Policy represents which snapshots are kept. A snapshot is kept if any of the rules keeps it.
+// The zero value keeps all the snapshots.
+type Policy struct {
+	KeepLast    int           // Keep the latest snapshots
+	KeepDaily   int           // Keep the latest snapshot of each of the latest days with snapshots
+	KeepWeekly  int           // Keep the latest snapshot of each of the latest weeks with snapshots
+	KeepMonthly int           // Keep the latest snapshot of each of the latest months with snapshots
+	KeepWithin  time.Duration // Keep the snapshots made within this duration before the latest one
+}
+
+// Report represents the result of applying a retention policy.
+type Report struct {
+	Removed []string // IDs of the snapshots removed
+	Kept    int      // Snapshots kept
+	Objects int      // Objects removed because no snapshot references them anymore
+	Bytes   int64    // Size of the objects removed
+}
+
+// IsZero returns whether the policy keeps all the snapshots.
+func (p Policy) IsZero() bool {
+	return p == Policy{}
+}
+
+// Run applies the retention policy provided to the snapshots with the name provided of the repository
+// of the path provided, removing the ones that the policy doesn't keep. Then, it removes the objects
+// that are not referenced by any snapshot of the repository.
+// Days, weeks and months are taken in the local time zone.
+// It reports the progress of removing snapshots and objects to the renderer of the options provided.
+// It must not run while other operations are writing to the repository, as their objects may not be referenced yet.
+// It stops and returns the error of the context provided when it's cancelled.
+func Run(ctx context.Context, path, name string, policy Policy, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	report := &Report{Removed: make([]string, 0, pkg.SliceSmallCapacity)}
+	if policy.IsZero() {
+		return report, nil
+	}
+
+	ids, err := snapshots.ListByName(path, name)
+	if err != nil {
+		return nil, err
+	}
+	keep := policy.apply(ids)
+	for i := range ids {
+		if keep[i] {
+			report.Kept++
+		}
+	}
+	if err := removeSnapshots(ctx, path, ids, keep, report, opts); err != nil {
+		return nil, err
+	}
+
+	if len(report.Removed) == 0 {
+		return report, nil
+	}
+	if err := removeUnreferenced(ctx, path, report, opts); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// apply returns whether each of the IDs provided, sorted by time, is kept by the policy.
+func (p Policy) apply(ids []snapshots.ID) []bool {
+	keep := make([]bool, len(ids))
+	if len(ids) == 0 {
+		return keep
+	}
+
+	latest := ids[len(ids)-1].Time
+	buckets := []struct {
+		n      int
+		format func(t time.Time) string
+	}{
+		{p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{p.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, b := range buckets {
+		last, count := "", 0
+		for i := len(ids) - 1; i >= 0 && count < b.n; i-- {
+			key := b.format(ids[i].Time.Local())
+			if key == last {
+				continue
+			}
+			keep[i] = true
+			last = key
+			count++
+		}
+	}
+
+	for i := range ids {
+		if len(ids)-i <= p.KeepLast || (p.KeepWithin > 0 && latest.Sub(ids[i].Time) <= p.KeepWithin) {
+			keep[i] = true
+		}
+	}
+	return keep
+}
+
+// removeSnapshots removes the snapshots of the IDs provided that are not kept, adding them to the report provided.
+func removeSnapshots(ctx context.Context, path string, ids []snapshots.ID, keep []bool, report *Report, opts *pkg.Options) error {
+	tracker := progress.NewTracker("forget", opts.Progress)
+	tracker.SetTotal(int64(len(ids)-report.Kept), 0)
+	tracker.Start(progress.DefaultInterval)
+	defer tracker.Finish()
+
+	for i, id := range ids {
+		if keep[i] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		opts.Log.Infof("Removing snapshot %s", id)
+		tracker.SetCurrentFile(id.String())
+		if err := os.Remove(id.Path(path)); err != nil {
+			return &os.PathError{
+				Op:   "remove snapshot",
+				Path: id.Path(path),
+				Err:  err,
+			}
+		}
+		tracker.Add(1, 0)
+		report.Removed = append(report.Removed, id.String())
+	}
+	return nil
+}
+
+// removeUnreferenced removes the objects of the repository of the path provided that are not referenced
+// by any of its snapshots, adding them to the report provided.
+func removeUnreferenced(ctx context.Context, path string, report *Report, opts *pkg.Options) error {
+	ids, err := snapshots.List(path)
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]bool, 10000)
+	for _, id := range ids {
+		snap, err := snapshots.Read(id.Path(path))
+		if err != nil {
+			return err
+		}
+		_ = snap.Walk(func(_ string, f *pkgFiles.File) error {
+			referenced[files.GetObjectName(f)] = true
+			return nil
+		})
+	}
+
+	objects, err := files.List(path)
+	if err != nil {
+		return err
+	}
+	unreferenced := make([]string, 0, pkg.SliceSmallCapacity)
+	sizes := make([]int64, 0, pkg.SliceSmallCapacity)
+	var totalSize int64
+	for _, objPath := range objects {
+		if referenced[filepath.Base(objPath)] {
+			continue
+		}
+
+		stat, err := os.Stat(objPath)
+		if err != nil {
+			return &os.PathError{
+				Op:   "stat object",
+				Path: objPath,
+				Err:  err,
+			}
+		}
+		unreferenced = append(unreferenced, objPath)
+		sizes = append(sizes, stat.Size())
+		totalSize += stat.Size()
+	}
+
+	tracker := progress.NewTracker("prune", opts.Progress)
+	tracker.SetTotal(int64(len(unreferenced)), totalSize)
+	tracker.Start(progress.DefaultInterval)
+	defer tracker.Finish()
+
+	for i, objPath := range unreferenced {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		opts.Log.Debugf("Removing unreferenced object %s", objPath)
+		tracker.SetCurrentFile(objPath)
+		if err := os.Remove(objPath); err != nil {
+			return &os.PathError{
+				Op:   "remove object",
+				Path: objPath,
+				Err:  err,
+			}
+		}
+		tracker.Add(1, sizes[i])
+		report.Objects++
+		report.Bytes += sizes[i]
+	}
+	return nil
+}
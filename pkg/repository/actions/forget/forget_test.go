@@ -0,0 +1,140 @@
+package forget_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/forget"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestForget")
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestRun(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(testingPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	day := func(d, h int) time.Time {
+		return time.Date(2020, 1, d, h, 0, 0, 0, time.Local)
+	}
+	writeSnapshot(snapshots.NewID("home", day(1, 10)), getFile("a", 10, "a"))
+	writeSnapshot(snapshots.NewID("home", day(2, 10)), getFile("a", 20, "b"))
+	writeSnapshot(snapshots.NewID("home", day(2, 12)), getFile("a", 30, "c"))
+	writeSnapshot(snapshots.NewID("home", day(3, 10)), getFile("a", 40, "d"))
+	writeSnapshot(snapshots.NewID("home", day(3, 12)), getFile("a", 10, "a"))
+	writeSnapshot(snapshots.NewID("other", day(1, 10)), getFile("a", 30, "c"))
+
+	// Zero policy
+	report, err := forget.Run(context.Background(), testingPath, "home", forget.Policy{}, nil)
+	if err != nil {
+		t.Fatalf("error applying zero policy: %s", err)
+	}
+	if len(report.Removed) != 0 {
+		t.Errorf("snapshots removed by zero policy: %v", report.Removed)
+	}
+
+	// Keep the latest and the latest of the last 2 days
+	status := bytes.NewBuffer(nil)
+	report, err = forget.Run(context.Background(), testingPath, "home", forget.Policy{KeepLast: 1, KeepDaily: 2}, &pkg.Options{Progress: progress.NewNDJSONRenderer(status)})
+	if err != nil {
+		t.Fatalf("error applying policy: %s", err)
+	}
+	checkProgress(status.Bytes(), map[string]progress.Event{
+		"forget": {Files: 3, TotalFiles: 3},
+		"prune":  {Files: 2, TotalFiles: 2, Bytes: 60, TotalBytes: 60},
+	}, t)
+	expected := []string{snapshots.NewID("home", day(1, 10)).String(), snapshots.NewID("home", day(2, 10)).String(), snapshots.NewID("home", day(3, 10)).String()}
+	if !reflect.DeepEqual(report.Removed, expected) || report.Kept != 2 {
+		t.Errorf("unexpected snapshots removed: %v, expected %v", report.Removed, expected)
+	}
+	// Only "bbb..." and "ddd..." are not referenced anymore
+	if report.Objects != 2 || report.Bytes != 60 {
+		t.Errorf("unexpected objects removed: %d (%d bytes)", report.Objects, report.Bytes)
+	}
+	objects, err := files.List(testingPath)
+	if err != nil || len(objects) != 2 {
+		t.Errorf("unexpected objects left: %v, %v", objects, err)
+	}
+	if ids, err := snapshots.ListByName(testingPath, "other"); err != nil || len(ids) != 1 {
+		t.Errorf("snapshots of other name removed: %v, %v", ids, err)
+	}
+
+	// Keep within
+	report, err = forget.Run(context.Background(), testingPath, "home", forget.Policy{KeepWithin: 2 * time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("error applying policy: %s", err)
+	}
+	if len(report.Removed) != 1 || report.Kept != 1 {
+		t.Errorf("unexpected result of keep within: %+v", report)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+// checkProgress checks that the progress events provided have a finish event for every operation expected
+// with the files and bytes expected.
+func checkProgress(status []byte, expected map[string]progress.Event, t *testing.T) {
+	found := make(map[string]progress.Event, len(expected))
+	for _, line := range bytes.Split(bytes.TrimSpace(status), []byte{'\n'}) {
+		var e progress.Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("cannot unmarshal progress event %s: %s", line, err)
+		}
+		if e.Type == progress.EventFinish {
+			found[e.Operation] = e
+		}
+	}
+
+	for op, exp := range expected {
+		e, ok := found[op]
+		if !ok {
+			t.Errorf("finish event of %s not found", op)
+			continue
+		}
+		if e.Files != exp.Files || e.TotalFiles != exp.TotalFiles || e.Bytes != exp.Bytes || e.TotalBytes != exp.TotalBytes {
+			t.Errorf("unexpected finish event of %s: %+v", op, e)
+		}
+	}
+}
+
+// getFile adds to the repo an object of the size provided filled with the character provided and returns its files.File.
+func getFile(name string, size int, char string) *pkgFiles.File {
+	content := []byte(strings.Repeat(char, size))
+	hash := sha256.Sum256(content)
+	_ = ioutil.WriteFile(files.GetPath(testingPath, hash[:], int64(size)), content, 0666)
+
+	return &pkgFiles.File{
+		Name: name,
+		Size: int64(size),
+		Hash: hash[:],
+	}
+}
+
+func writeSnapshot(id snapshots.ID, f *pkgFiles.File) {
+	_ = snapshots.Write(id.Path(testingPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{f},
+		Dirs:  []pkgFiles.Dir{},
+	})
+}
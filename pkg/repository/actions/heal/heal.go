@@ -0,0 +1,256 @@
+package heal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Heal takes the repo path, finds the corrupted objects of that repo, moves them to the quarantine folder,
+// and looks for a correct copy of them in the source directories provided to add it back to the repo.
+// Objects that cannot be checked (for example, because of permission or I/O errors) are left in place.
+// It writes a report of the corrupted objects, the snapshots that referenced them, whether they were
+// healed and the objects that couldn't be checked in the writer provided in an human-readable way
+// or in JSON depending of the bool provided. If any object couldn't be checked, it returns an error
+// after writing the report.
+// It stops and returns the error of the context provided when it's cancelled. If it fails or it's cancelled
+// after moving objects to the quarantine folder, it writes the report with the objects moved until then
+// before returning the error.
+func Heal(ctx context.Context, path string, sources []string, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	opts = opts.Normalize()
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return fmt.Errorf("error reading settings: %w", err)
+	}
+
+	// Find corrupted objects
+	corrupted, failures, err := check.FindCorrupted(ctx, path, opts)
+	if err != nil {
+		return fmt.Errorf("error checking repository: %w", err)
+	}
+
+	report := Report{
+		Objects:  make([]*Object, 0, len(corrupted)),
+		Failures: make([]Failure, 0, len(failures)),
+	}
+	for _, f := range failures {
+		report.Failures = append(report.Failures, Failure{
+			Path:  f.Path,
+			Error: f.Err.Error(),
+		})
+	}
+	objects := make(map[string]*Object, len(corrupted))
+	for _, objPath := range corrupted {
+		obj := &Object{
+			Name:       filepath.Base(objPath),
+			References: make([]Reference, 0, 10),
+		}
+		objects[obj.Name] = obj
+	}
+
+	// Find the snapshots that reference them before moving anything
+	if len(objects) != 0 {
+		if err := findReferences(path, objects); err != nil {
+			return err
+		}
+	}
+
+	// Quarantine them and look for copies. From here, the report is written even if it fails
+	err = healObjects(ctx, path, sett.HashAlgorithm, corrupted, sources, objects, &report, opts)
+	if writeErr := writeReport(report, inJson, writeTo); writeErr != nil && err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Failures) != 0 {
+		return fmt.Errorf("%d objects couldn't be checked", len(report.Failures))
+	}
+	return nil
+}
+
+// healObjects moves the corrupted objects of the paths provided to the quarantine folder, adding them
+// to the report provided as they're moved, and then looks for copies of them in the sources provided.
+func healObjects(ctx context.Context, repoPath, hashAlgorithm string, corrupted, sources []string, objects map[string]*Object, report *Report, opts *pkg.Options) error {
+	for _, objPath := range corrupted {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		obj := objects[filepath.Base(objPath)]
+		quarantinePath, err := quarantine(repoPath, objPath)
+		if err != nil {
+			return err
+		}
+		obj.QuarantinePath = quarantinePath
+		report.Objects = append(report.Objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil
+	}
+	return findCopies(ctx, repoPath, hashAlgorithm, sources, objects, opts)
+}
+
+// writeReport writes the report provided in the writer provided in an human-readable way or in JSON
+// depending of the bool provided.
+func writeReport(report Report, inJson bool, writeTo io.Writer) error {
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	return nil
+}
+
+// quarantine moves the object of the path provided to the quarantine folder of the repo
+// and returns its new path.
+func quarantine(repoPath, objPath string) (string, error) {
+	quarantineFolderPath := filepath.Join(repoPath, repository.QuarantineFolderName)
+	if err := os.MkdirAll(quarantineFolderPath, pkg.DefaultDirPerm); err != nil {
+		return "", &os.PathError{
+			Op:   "create quarantine folder",
+			Path: quarantineFolderPath,
+			Err:  err,
+		}
+	}
+
+	// Find a free name, in case that the same object was quarantined before
+	name := filepath.Base(objPath)
+	newPath := filepath.Join(quarantineFolderPath, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(newPath); os.IsNotExist(err) {
+			break
+		}
+		newPath = filepath.Join(quarantineFolderPath, name+"."+strconv.Itoa(i))
+	}
+
+	if err := os.Rename(objPath, newPath); err != nil {
+		return "", &os.PathError{
+			Op:   "move to quarantine",
+			Path: objPath,
+			Err:  err,
+		}
+	}
+	return newPath, nil
+}
+
+// findReferences reads all the snapshots of the repo and adds to the objects provided
+// the snapshots and paths that reference them.
+func findReferences(repoPath string, objects map[string]*Object) error {
+	ids, err := snapshots.List(repoPath)
+	if err != nil {
+		return fmt.Errorf("cannot get snapshots: %w", err)
+	}
+
+	for _, id := range ids {
+		snap, err := snapshots.Read(id.Path(repoPath))
+		if err != nil {
+			return err
+		}
+
+		_ = snap.Walk(func(path string, f *pkgFiles.File) error {
+			if obj, ok := objects[files.GetObjectName(f)]; ok {
+				obj.References = append(obj.References, Reference{
+					Snapshot: id.String(),
+					Path:     path,
+				})
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// findCopies walks the source directories provided looking for files with the same hash and size
+// than the objects provided. When one is found, it's added back to the repo.
+// It stops and returns the error of the context provided when it's cancelled.
+func findCopies(ctx context.Context, repoPath, hashAlgorithm string, sources []string, objects map[string]*Object, opts *pkg.Options) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	buf := make([]byte, opts.BufferSize)
+
+	h, err := hasher.New(hashAlgorithm, opts)
+	if err != nil {
+		return err
+	}
+
+	// Index wanted sizes to avoid hashing files that cannot match
+	wantedSizes := make(map[int64]int, len(objects))
+	for name := range objects {
+		_, size, _ := files.GetDataFromName(name)
+		wantedSizes[size]++
+	}
+
+	for _, source := range sources {
+		err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil || !info.Mode().IsRegular() || wantedSizes[info.Size()] == 0 {
+				return nil
+			}
+
+			hash, err := h.HashPath(path)
+			if err != nil {
+				return nil
+			}
+			obj, ok := objects[files.GetName(hash, info.Size())]
+			if !ok || obj.HealedFrom != "" {
+				return nil
+			}
+
+			if err := restoreObject(ctx, files.GetPath(repoPath, hash, info.Size()), path, hash, h, buf); err != nil {
+				return nil
+			}
+			obj.HealedFrom = path
+			wantedSizes[info.Size()]--
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error walking source %s: %w", source, err)
+		}
+	}
+	return ctx.Err()
+}
+
+// restoreObject copies the file from the path provided to the object path provided,
+// and checks that the copy has the hash expected. If it doesn't, the copy is removed.
+func restoreObject(ctx context.Context, objPath, srcPath string, expectedHash []byte, h *hasher.Hasher, buf []byte) error {
+	if err := os.MkdirAll(filepath.Dir(objPath), pkg.DefaultDirPerm); err != nil {
+		return err
+	}
+	if err := utils.CopyFileContext(ctx, srcPath, objPath, buf); err != nil {
+		return err
+	}
+
+	actualHash, err := h.HashPath(objPath)
+	if err == nil && !bytes.Equal(actualHash, expectedHash) {
+		err = fmt.Errorf("hashes don't match in file %s", objPath)
+	}
+	if err != nil {
+		_ = os.Remove(objPath)
+		return err
+	}
+	return nil
+}
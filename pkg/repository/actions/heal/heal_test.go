@@ -0,0 +1,195 @@
+package heal_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/heal"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestHeal")
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestHeal(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	repoPath := filepath.Join(testingPath, "repo")
+	sourcePath := filepath.Join(testingPath, "source")
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	good, corrupted, lost := []byte("good content"), []byte("corrupted content"), []byte("lost content")
+	goodPath := addObject(repoPath, good, good, t)
+	corruptedPath := addObject(repoPath, corrupted, []byte("CORRUPTED content"), t)
+	lostPath := addObject(repoPath, lost, []byte("lost"), t)
+	writeFile(filepath.Join(sourcePath, "dir", "copy"), corrupted, t)
+
+	id := snapshots.NewID("mypc", time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC))
+	if err := snapshots.Write(id.Path(repoPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{getFile("a", corrupted), getFile("b", good)},
+		Dirs: []pkgFiles.Dir{{
+			Name:  "dir",
+			Files: []*pkgFiles.File{getFile("c", corrupted)},
+		}},
+	}); err != nil {
+		t.Fatalf("error writing snapshot: %s", err)
+	}
+
+	output := bytes.NewBuffer(nil)
+	if err := heal.Heal(context.Background(), repoPath, []string{sourcePath}, nil, true, output); err != nil {
+		t.Fatalf("error healing repo: %s", err)
+	}
+
+	var report heal.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if len(report.Objects) != 2 {
+		t.Fatalf("unexpected number of corrupted objects: %d", len(report.Objects))
+	}
+	for _, obj := range report.Objects {
+		switch obj.Name {
+		case filepath.Base(corruptedPath):
+			if obj.HealedFrom != filepath.Join(sourcePath, "dir", "copy") {
+				t.Errorf("object %s healed from unexpected source: %s", obj.Name, obj.HealedFrom)
+			}
+			if len(obj.References) != 2 || obj.References[0].Path != "a" || obj.References[1].Path != "dir/c" {
+				t.Errorf("unexpected references of object %s: %+v", obj.Name, obj.References)
+			}
+			if obj.References[0].Snapshot != "mypc/2019-12-31_23-59-59" {
+				t.Errorf("unexpected snapshot in references of object %s: %s", obj.Name, obj.References[0].Snapshot)
+			}
+			checkContent(corruptedPath, corrupted, t)
+		case filepath.Base(lostPath):
+			if obj.HealedFrom != "" {
+				t.Errorf("object %s healed from unexpected source: %s", obj.Name, obj.HealedFrom)
+			}
+			if len(obj.References) != 0 {
+				t.Errorf("unexpected references of object %s: %+v", obj.Name, obj.References)
+			}
+			if _, err := os.Stat(lostPath); !os.IsNotExist(err) {
+				t.Errorf("object %s was not removed from the files folder", obj.Name)
+			}
+		default:
+			t.Errorf("unexpected corrupted object: %s", obj.Name)
+			continue
+		}
+		if _, err := os.Stat(obj.QuarantinePath); err != nil {
+			t.Errorf("object %s not found in quarantine: %s", obj.Name, err)
+		}
+	}
+	checkContent(goodPath, good, t)
+}
+
+func TestHeal_InvalidSnapshot(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	repoPath := filepath.Join(testingPath, "repo")
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	corrupted := []byte("corrupted content")
+	corruptedPath := addObject(repoPath, corrupted, []byte("CORRUPTED content"), t)
+	id := snapshots.NewID("mypc", time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC))
+	writeFile(id.Path(repoPath), []byte("not a snapshot"), t)
+
+	if err := heal.Heal(context.Background(), repoPath, nil, nil, true, ioutil.Discard); err == nil {
+		t.Error("not error healing repo with invalid snapshot")
+	}
+	if _, err := os.Stat(corruptedPath); err != nil {
+		t.Errorf("corrupted object moved before reading the snapshots: %s", err)
+	}
+}
+
+func TestHeal_Unreadable(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	repoPath := filepath.Join(testingPath, "repo")
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	unreadable, corrupted := []byte("unreadable content"), []byte("corrupted content")
+	unreadablePath := addObject(repoPath, unreadable, unreadable, t)
+	corruptedPath := addObject(repoPath, corrupted, []byte("CORRUPTED content"), t)
+	if err := os.Chmod(unreadablePath, 0); err != nil {
+		t.Fatalf("error changing permissions of %s: %s", unreadablePath, err)
+	}
+	defer os.Chmod(unreadablePath, 0666)
+	if _, err := ioutil.ReadFile(unreadablePath); err == nil {
+		t.Skip("files without permissions can be read by the current user")
+	}
+
+	output := bytes.NewBuffer(nil)
+	if err := heal.Heal(context.Background(), repoPath, nil, nil, true, output); err == nil {
+		t.Error("not error healing repo with unreadable objects")
+	}
+
+	var report heal.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if len(report.Objects) != 1 || report.Objects[0].Name != filepath.Base(corruptedPath) {
+		t.Errorf("unexpected corrupted objects: %+v", report.Objects)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Path != unreadablePath || report.Failures[0].Error == "" {
+		t.Errorf("unexpected failures: %+v", report.Failures)
+	}
+	if _, err := os.Stat(unreadablePath); err != nil {
+		t.Errorf("unreadable object was moved: %s", err)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func getFile(name string, content []byte) *pkgFiles.File {
+	hash := sha256.Sum256(content)
+	return &pkgFiles.File{
+		Name: name,
+		Size: int64(len(content)),
+		Hash: hash[:],
+	}
+}
+
+// addObject adds to the repo an object named after the content provided, but with the data provided.
+func addObject(repoPath string, content, data []byte, t *testing.T) string {
+	f := getFile("", content)
+	path := files.GetPath(repoPath, f.Hash, f.Size)
+	writeFile(path, data, t)
+	return path
+}
+
+func writeFile(path string, data []byte, t *testing.T) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatalf("cannot create dir %s: %s", filepath.Dir(path), err)
+	}
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		t.Fatalf("cannot write file %s: %s", path, err)
+	}
+}
+
+func checkContent(path string, expected []byte, t *testing.T) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("cannot read file %s: %s", path, err)
+		return
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("unexpected content in %s: %s", path, string(data))
+	}
+}
@@ -0,0 +1,77 @@
+package heal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of healing a repository.
+type Report struct {
+	Objects  []*Object `json:"objects"`
+	Failures []Failure `json:"failures"` // Objects that couldn't be checked, left in place
+}
+
+// Object represents a corrupted object found in the repository.
+type Object struct {
+	Name           string      `json:"name"`
+	QuarantinePath string      `json:"quarantine_path"`
+	References     []Reference `json:"references"`
+	HealedFrom     string      `json:"healed_from"`
+}
+
+// Failure represents an object of the repository that couldn't be checked.
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Reference represents a file of a snapshot that points to a corrupted object.
+type Reference struct {
+	Snapshot string `json:"snapshot"`
+	Path     string `json:"path"`
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	for _, f := range report.Failures {
+		_, _ = fmt.Fprintf(buf, "cannot check %s: %s\n", f.Path, f.Error)
+	}
+	if len(report.Failures) != 0 {
+		_ = buf.WriteByte('\n')
+	}
+
+	if len(report.Objects) == 0 {
+		_, _ = buf.WriteString("No corrupted objects found\n")
+		return buf.Bytes()
+	}
+
+	healed := 0
+	for _, obj := range report.Objects {
+		_, _ = fmt.Fprintf(buf, "%s\n- quarantined in %s\n", obj.Name, obj.QuarantinePath)
+		if obj.HealedFrom != "" {
+			healed++
+			_, _ = fmt.Fprintf(buf, "- healed from %s\n", obj.HealedFrom)
+		} else {
+			_, _ = buf.WriteString("- not healed\n")
+		}
+
+		if len(obj.References) == 0 {
+			_, _ = buf.WriteString("- not referenced by any snapshot\n")
+		}
+		for _, ref := range obj.References {
+			_, _ = fmt.Fprintf(buf, "- referenced by %s: %s\n", ref.Snapshot, ref.Path)
+		}
+		_ = buf.WriteByte('\n')
+	}
+	_, _ = fmt.Fprintf(buf, "Corrupted objects: %d (healed: %d)\n", len(report.Objects), healed)
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
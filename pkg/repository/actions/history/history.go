@@ -0,0 +1,147 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Version represents a distinct content of a file across the snapshots.
+type Version struct {
+	Number    int    `json:"version"`
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+	First     string `json:"first_snapshot"`
+	Last      string `json:"last_snapshot"`
+	Snapshots int    `json:"snapshots"`
+
+	file *pkgFiles.File // File of the first snapshot with the version
+}
+
+// GetVersions takes the repo path and returns the distinct versions of the file of the path provided
+// in the snapshots with the name provided, numbered from 1 in the order they first appear.
+// Two files are considered the same version if they are stored in the same object.
+func GetVersions(path, name, filePath string) ([]Version, error) {
+	ids, err := snapshots.ListByName(path, name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get snapshots: %w", err)
+	}
+
+	versions := make([]Version, 0, 10)
+	index := make(map[string]int, 10) // Index of every version in the slice, by object name
+	for _, id := range ids {
+		snap, err := snapshots.Read(id.Path(path))
+		if err != nil {
+			return nil, err
+		}
+
+		f := snap.Get(filePath)
+		if f == nil {
+			continue
+		}
+
+		objName := files.GetObjectName(f)
+		i, ok := index[objName]
+		if !ok {
+			i = len(versions)
+			index[objName] = i
+			versions = append(versions, Version{
+				Number: i + 1,
+				Hash:   fmt.Sprintf("%x", f.Hash),
+				Size:   f.Size,
+				First:  id.String(),
+				file:   f,
+			})
+		}
+		versions[i].Last = id.String()
+		versions[i].Snapshots++
+	}
+	return versions, nil
+}
+
+// History takes the repo path and writes the distinct versions of the file of the path provided
+// in the snapshots with the name provided (see GetVersions) in the writer provided in an human-readable way
+// or in JSON depending of the bool provided.
+func History(path, name, filePath string, inJson bool, writeTo io.Writer) error {
+	versions, err := GetVersions(path, name, filePath)
+	if err != nil {
+		return err
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(versions)
+	} else {
+		output = getTXT(versions)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write history to writer provided: %w", err)
+	}
+	return nil
+}
+
+// RestoreVersion takes the repo path and restores the version with the number provided of the file of the path
+// provided in the snapshots with the name provided (see GetVersions) in the destination provided.
+// If the destination is an existing directory, the file will be restored inside it with its original name.
+// It will not overwrite existing files. The content restored is verified, returning an error wrapping
+// files.ErrMismatch if it doesn't match the version.
+// It stops and returns the error of the context provided when it's cancelled, removing the file restored.
+func RestoreVersion(ctx context.Context, path, name, filePath string, version int, destination string, opts *pkg.Options) error {
+	opts = opts.Normalize()
+
+	versions, err := GetVersions(path, name, filePath)
+	if err != nil {
+		return err
+	}
+	if version < 1 || version > len(versions) {
+		return fmt.Errorf("version %d of %s not found", version, filePath)
+	}
+	v := versions[version-1]
+
+	// Get destination
+	if stat, err := os.Stat(destination); err == nil {
+		if !stat.IsDir() {
+			return &os.PathError{
+				Op:   "restore version",
+				Path: destination,
+				Err:  os.ErrExist,
+			}
+		}
+		destination = filepath.Join(destination, filepath.Base(filepath.FromSlash(filePath)))
+		if _, err := os.Stat(destination); err == nil {
+			return &os.PathError{
+				Op:   "restore version",
+				Path: destination,
+				Err:  os.ErrExist,
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return &os.PathError{
+			Op:   "stat destination",
+			Path: destination,
+			Err:  err,
+		}
+	}
+
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return fmt.Errorf("error reading settings: %w", err)
+	}
+	h, err := hasher.NewHash(sett.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+	return files.CopyObject(ctx, files.GetObjectPath(path, v.file), destination, v.file.Hash, v.file.Size, h, make([]byte, opts.BufferSize))
+}
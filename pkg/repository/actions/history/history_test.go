@@ -0,0 +1,151 @@
+package history_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/history"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestHistory")
+	repoPath    = filepath.Join(testingPath, "repo")
+	contents    = [][]byte{[]byte("first version"), []byte("second version"), nil, []byte("first version")}
+)
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestHistory(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	for i, content := range contents {
+		writeSnapshot(snapshots.NewID("mypc", time.Date(2019, 1, i+1, 0, 0, 0, 0, time.UTC)), content)
+	}
+	writeSnapshot(snapshots.NewID("laptop", time.Date(2019, 1, 5, 0, 0, 0, 0, time.UTC)), []byte("other version"))
+
+	expected := history.HistoryJSON{Versions: []history.Version{
+		{
+			Number:    1,
+			Hash:      fmt.Sprintf("%x", sha256.Sum256(contents[0])),
+			Size:      int64(len(contents[0])),
+			First:     "mypc/2019-01-01_00-00-00",
+			Last:      "mypc/2019-01-04_00-00-00",
+			Snapshots: 2,
+		},
+		{
+			Number:    2,
+			Hash:      fmt.Sprintf("%x", sha256.Sum256(contents[1])),
+			Size:      int64(len(contents[1])),
+			First:     "mypc/2019-01-02_00-00-00",
+			Last:      "mypc/2019-01-02_00-00-00",
+			Snapshots: 1,
+		},
+	}}
+
+	output := bytes.NewBuffer(nil)
+	if err := history.History(repoPath, "mypc", "/docs/a.txt", true, output); err != nil {
+		t.Fatalf("error getting history: %s", err)
+	}
+	var actual history.HistoryJSON
+	if err := json.Unmarshal(output.Bytes(), &actual); err != nil {
+		t.Fatalf("cannot unmarshal history %s: %s", output.String(), err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("history doesn't match the expected result\n-> Expected: %+v\n-> Found: %+v", expected, actual)
+	}
+
+	// Restore in a directory and in a new file
+	if err := history.RestoreVersion(context.Background(), repoPath, "mypc", "docs/a.txt", 2, testingPath, nil); err != nil {
+		t.Fatalf("error restoring version in directory: %s", err)
+	}
+	checkContent(filepath.Join(testingPath, "a.txt"), contents[1], t)
+	if err := history.RestoreVersion(context.Background(), repoPath, "mypc", "docs/a.txt", 1, filepath.Join(testingPath, "b.txt"), nil); err != nil {
+		t.Fatalf("error restoring version in file: %s", err)
+	}
+	checkContent(filepath.Join(testingPath, "b.txt"), contents[0], t)
+
+	// Invalid cases
+	if err := history.RestoreVersion(context.Background(), repoPath, "mypc", "docs/a.txt", 1, testingPath, nil); err == nil {
+		t.Error("not error restoring version over existing file")
+	}
+	if err := history.RestoreVersion(context.Background(), repoPath, "mypc", "docs/a.txt", 3, filepath.Join(testingPath, "c.txt"), nil); err == nil {
+		t.Error("not error restoring not existing version")
+	}
+
+	// Cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := history.RestoreVersion(ctx, repoPath, "mypc", "docs/a.txt", 1, filepath.Join(testingPath, "c.txt"), nil); err == nil {
+		t.Error("not error restoring version with context cancelled")
+	}
+	if _, err := os.Stat(filepath.Join(testingPath, "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("file restored with context cancelled: %v", err)
+	}
+}
+
+func TestRestoreVersion_Collision(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	// The object without collision index has other content with the same hash and size
+	content := []byte("collided version")
+	hash := sha256.Sum256(content)
+	f := &pkgFiles.File{Name: "a.txt", Size: int64(len(content)), Hash: hash[:], Collision: 1}
+	_ = ioutil.WriteFile(files.GetPath(repoPath, f.Hash, f.Size), []byte("COLLIDED version"), 0666)
+	_ = ioutil.WriteFile(files.GetObjectPath(repoPath, f), content, 0666)
+	id := snapshots.NewID("mypc", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	_ = snapshots.Write(id.Path(repoPath), &snapshots.Snapshot{
+		Dirs: []pkgFiles.Dir{{Name: "docs", Files: []*pkgFiles.File{f}}},
+	})
+
+	destination := filepath.Join(testingPath, "a.txt")
+	if err := history.RestoreVersion(context.Background(), repoPath, "mypc", "docs/a.txt", 1, destination, nil); err != nil {
+		t.Fatalf("error restoring version with collision index: %s", err)
+	}
+	checkContent(destination, content, t)
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+// writeSnapshot writes a snapshot where docs/a.txt has the content provided, or doesn't exist if it's nil.
+func writeSnapshot(id snapshots.ID, content []byte) {
+	d := pkgFiles.Dir{Name: "docs", Files: []*pkgFiles.File{}}
+	if content != nil {
+		hash := sha256.Sum256(content)
+		_ = ioutil.WriteFile(files.GetPath(repoPath, hash[:], int64(len(content))), content, 0666)
+		d.Files = append(d.Files, &pkgFiles.File{Name: "a.txt", Size: int64(len(content)), Hash: hash[:]})
+	}
+	_ = snapshots.Write(id.Path(repoPath), &snapshots.Snapshot{Dirs: []pkgFiles.Dir{d}})
+}
+
+func checkContent(path string, expected []byte, t *testing.T) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("cannot read file %s: %s", path, err)
+		return
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("unexpected content in %s: %s", path, string(data))
+	}
+}
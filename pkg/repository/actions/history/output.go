@@ -0,0 +1,31 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type HistoryJSON struct {
+	Versions []Version `json:"versions"`
+}
+
+// getTXT returns a easily-readable representation of the versions provided.
+func getTXT(versions []Version) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	if len(versions) == 0 {
+		_, _ = buf.WriteString("No versions found\n")
+	}
+	for _, v := range versions {
+		_, _ = fmt.Fprintf(buf, "Version %d\n- hash: %s\n- size: %d bytes\n- first: %s\n- last: %s\n- snapshots: %d\n\n",
+			v.Number, v.Hash, v.Size, v.First, v.Last, v.Snapshots)
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the versions provided.
+func getJSON(versions []Version) []byte {
+	data, _ := json.Marshal(HistoryJSON{Versions: versions})
+	return append(data, '\n')
+}
@@ -0,0 +1,190 @@
+package importTar
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gzipMagic is the header that all gzip streams start with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Import takes the repo path, reads the tar archive (optionally compressed with gzip) provided,
+// stores its regular files in the repo and writes a snapshot with the name provided that reflects
+// the tree of the archive, keeping the permissions and modification times of its entries.
+// Entries that are neither directories, regular files nor hard links to regular files are skipped.
+// If the context provided is cancelled, it stops and writes a snapshot with the files imported
+// until then marked as incomplete, returning the error of the context.
+// It writes a report of the import in the writer provided in an human-readable way or in JSON
+// depending of the bool provided.
+func Import(ctx context.Context, path, name string, r io.Reader, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	opts = opts.Normalize()
+	id := snapshots.NewID(name, time.Now())
+
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return fmt.Errorf("error reading settings: %w", err)
+	}
+	h, err := hasher.NewHash(sett.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(id.Path(path)); err == nil {
+		return fmt.Errorf("snapshot %s already exists", id)
+	}
+
+	// Decompress if needed
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("error reading gzip stream: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+
+	root := newNode()
+	report := &Report{
+		Snapshot: id.String(),
+		Skipped:  make([]string, 0, 10),
+	}
+	buf := make([]byte, opts.BufferSize)
+	tr := tar.NewReader(r)
+	for ctx.Err() == nil {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+
+		entryPath, ok := cleanPath(header.Name)
+		if !ok {
+			report.Skipped = append(report.Skipped, header.Name)
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			d := root.getDir(entryPath)
+			d.mode, d.modTime = getInfo(header)
+		// Old archives use '\x00' for regular files, that older versions of archive/tar
+		// don't report as tar.TypeReg. tar.TypeRegA is not used because it's deprecated.
+		case tar.TypeReg, '\x00':
+			f, err := storeFile(path, utils.NewContextReader(ctx, tr), h, buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					continue // Interrupted, the loop will end
+				}
+				return fmt.Errorf("error storing %s: %w", header.Name, err)
+			}
+			f.Mode, f.ModTime = getInfo(header)
+			root.addFile(entryPath, f)
+			report.Files++
+			report.Bytes += f.Size
+		case tar.TypeLink:
+			linkPath, ok := cleanPath(header.Linkname)
+			target := root.getFile(linkPath)
+			if !ok || target == nil {
+				report.Skipped = append(report.Skipped, header.Name)
+				continue
+			}
+			f := &pkgFiles.File{Size: target.Size, Hash: target.Hash}
+			f.Mode, f.ModTime = getInfo(header)
+			root.addFile(entryPath, f)
+			report.Files++
+			report.Bytes += target.Size
+		default:
+			report.Skipped = append(report.Skipped, header.Name)
+		}
+	}
+
+	// Write snapshot
+	d := root.toDir("")
+	if err := snapshots.Write(id.Path(path), &snapshots.Snapshot{
+		Version:    internal.Version,
+		Incomplete: ctx.Err() != nil,
+		Dirs:       d.Dirs,
+		Files:      d.Files,
+	}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("import interrupted, incomplete snapshot %s saved: %w", id, err)
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	return nil
+}
+
+// storeFile stores the content of the reader provided in the repo and returns its files.File.
+func storeFile(repoPath string, r io.Reader, h hash.Hash, buf []byte) (*pkgFiles.File, error) {
+	obj, err := files.NewTempObject(repoPath, h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyBuffer(obj, r, buf); err != nil {
+		_ = obj.Discard()
+		return nil, err
+	}
+
+	hash, size, err := obj.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &pkgFiles.File{Size: size, Hash: hash}, nil
+}
+
+// getInfo returns the permissions and the modification time of the archive entry provided,
+// or nil if the entry doesn't have modification time.
+func getInfo(header *tar.Header) (os.FileMode, *time.Time) {
+	mode := os.FileMode(header.Mode).Perm()
+	if header.ModTime.IsZero() {
+		return mode, nil
+	}
+	modTime := header.ModTime.UTC()
+	return mode, &modTime
+}
+
+// cleanPath returns the path of an archive entry relative to the root of the snapshot.
+// It returns false if the path is the root itself or points outside of it.
+func cleanPath(p string) (string, bool) {
+	p = strings.TrimLeft(path.Clean("/"+p), "/")
+	if p == "" {
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,195 @@
+package importTar_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/importTar"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestImport")
+	modTime     = time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)
+)
+
+type entry struct {
+	header  tar.Header
+	content string
+}
+
+var entries = []entry{
+	{tar.Header{Typeflag: tar.TypeDir, Name: "./"}, ""},
+	{tar.Header{Typeflag: tar.TypeReg, Name: "./a"}, "first content"},
+	{tar.Header{Typeflag: tar.TypeDir, Name: "./dir/"}, ""},
+	{tar.Header{Typeflag: tar.TypeReg, Name: "./dir/b"}, "second content"},
+	{tar.Header{Typeflag: tar.TypeSymlink, Name: "./dir/symlink", Linkname: "b"}, ""},
+	{tar.Header{Typeflag: tar.TypeLink, Name: "./dir/link", Linkname: "./a"}, ""},
+	{tar.Header{Typeflag: tar.TypeReg, Name: "../other/c"}, "first content"},
+	{tar.Header{Typeflag: tar.TypeDir, Name: "empty/"}, ""},
+}
+
+// expected is the JSON representation of the tree expected from entries
+const expected = `{"version":"v1.0.0","dirs":[{"name":"dir","mode":420,"mod_time":"2019-12-31T23:59:59Z","dirs":[],"files":[{"name":"b","size":14,"hash":"C7Ln1S+M5RMeZMlfEcpkOkT8qdOVS+murXgei8NG3FA=","mode":420,"mod_time":"2019-12-31T23:59:59Z"},{"name":"link","size":13,"hash":"LNSDfHcm9wBHyP2vtSgB2/7yy097xM+y4EQZgPnUo7g=","mode":420,"mod_time":"2019-12-31T23:59:59Z"}]},{"name":"other","dirs":[],"files":[{"name":"c","size":13,"hash":"LNSDfHcm9wBHyP2vtSgB2/7yy097xM+y4EQZgPnUo7g=","mode":420,"mod_time":"2019-12-31T23:59:59Z"}]},{"name":"empty","mode":420,"mod_time":"2019-12-31T23:59:59Z","dirs":[],"files":[]}],"files":[{"name":"a","size":13,"hash":"LNSDfHcm9wBHyP2vtSgB2/7yy097xM+y4EQZgPnUo7g=","mode":420,"mod_time":"2019-12-31T23:59:59Z"}]}`
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestImport(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(testingPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	// Tar
+	report := doImport("plain", getTar(false, t), t)
+	if report.Files != 4 || report.Bytes != 53 || len(report.Skipped) != 2 {
+		t.Errorf("unexpected report importing tar: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, t)
+
+	// Tar.gz
+	report = doImport("gzip", getTar(true, t), t)
+	if report.Files != 4 || report.Bytes != 53 || len(report.Skipped) != 2 {
+		t.Errorf("unexpected report importing tar.gz: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, t)
+
+	// Old archive with regular files typed as '\x00'
+	archive := getTar(false, t).Bytes()
+	setTypeflag(archive, "./a", '\x00', t)
+	report = doImport("old", bytes.NewBuffer(archive), t)
+	if report.Files != 4 || report.Bytes != 53 || len(report.Skipped) != 2 {
+		t.Errorf("unexpected report importing old tar: %+v", report)
+	}
+	checkSnapshot(report.Snapshot, t)
+
+	// Invalid archive
+	if err := importTar.Import(context.Background(), testingPath, "invalid", bytes.NewReader([]byte("not an archive")), nil, true, ioutil.Discard); err == nil {
+		t.Error("not error importing invalid archive")
+	}
+
+	// Cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := importTar.Import(ctx, testingPath, "cancelled", getTar(false, t), nil, true, ioutil.Discard); err == nil {
+		t.Error("not error importing with context cancelled")
+	}
+	ids, err := snapshots.ListByName(testingPath, "cancelled")
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("incomplete snapshot not found: %v, %v", ids, err)
+	}
+	if snap, err := snapshots.Read(ids[0].Path(testingPath)); err != nil || !snap.Incomplete {
+		t.Errorf("snapshot not marked as incomplete: %+v, %v", snap, err)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func getTar(compress bool, t *testing.T) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	var gw *gzip.Writer
+	tw := tar.NewWriter(buf)
+	if compress {
+		gw = gzip.NewWriter(buf)
+		tw = tar.NewWriter(gw)
+	}
+
+	for _, e := range entries {
+		h := e.header
+		h.Size = int64(len(e.content))
+		h.Mode = 0644
+		h.ModTime = modTime
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatalf("error writing header of %s: %s", h.Name, err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatalf("error writing content of %s: %s", h.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar: %s", err)
+	}
+	if compress {
+		if err := gw.Close(); err != nil {
+			t.Fatalf("error closing gzip: %s", err)
+		}
+	}
+	return buf
+}
+
+// setTypeflag changes the type of the entry with the name provided in the tar archive provided,
+// as archive/tar doesn't write entries with deprecated types.
+func setTypeflag(archive []byte, name string, typeflag byte, t *testing.T) {
+	const blockSize = 512
+	for offset := 0; offset+blockSize <= len(archive); offset += blockSize {
+		block := archive[offset : offset+blockSize]
+		if string(bytes.TrimRight(block[:100], "\x00")) != name {
+			continue
+		}
+
+		// Update the typeflag and the checksum
+		block[156] = typeflag
+		copy(block[148:156], "        ")
+		sum := 0
+		for _, b := range block {
+			sum += int(b)
+		}
+		copy(block[148:156], fmt.Sprintf("%06o\x00 ", sum))
+		return
+	}
+	t.Fatalf("entry %s not found in archive", name)
+}
+
+func doImport(name string, archive *bytes.Buffer, t *testing.T) importTar.Report {
+	output := bytes.NewBuffer(nil)
+	if err := importTar.Import(context.Background(), testingPath, name, archive, nil, true, output); err != nil {
+		t.Fatalf("error importing %s: %s", name, err)
+	}
+
+	var report importTar.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	return report
+}
+
+func checkSnapshot(snapshot string, t *testing.T) {
+	id, err := snapshots.ParseID(snapshot)
+	if err != nil {
+		t.Errorf("error parsing snapshot %s: %s", snapshot, err)
+		return
+	}
+	snap, err := snapshots.Read(id.Path(testingPath))
+	if err != nil {
+		t.Errorf("error reading snapshot %s: %s", snapshot, err)
+		return
+	}
+
+	data, _ := json.Marshal(snap)
+	if string(data) != expected {
+		t.Errorf("snapshot doesn't match the expected result\n-> Expected: %s\n-> Found: %s", expected, string(data))
+	}
+	_ = snap.Walk(func(path string, f *pkgFiles.File) error {
+		if _, err := os.Stat(files.GetPath(testingPath, f.Hash, f.Size)); err != nil {
+			t.Errorf("object of %s not found: %s", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package importTar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of importing an archive.
+type Report struct {
+	Snapshot string   `json:"snapshot"`
+	Files    int      `json:"files"`
+	Bytes    int64    `json:"bytes"`
+	Skipped  []string `json:"skipped"`
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	for _, entry := range report.Skipped {
+		_, _ = fmt.Fprintf(buf, "- skipped %s\n", entry)
+	}
+	_, _ = fmt.Fprintf(buf, "Snapshot %s created\nFiles imported: %d (%d bytes, skipped: %d)\n",
+		report.Snapshot, report.Files, report.Bytes, len(report.Skipped))
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
@@ -0,0 +1,123 @@
+package importTar
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"os"
+	"strings"
+	"time"
+)
+
+// node represents a directory of the tree of the archive while it's being read.
+// Children keep the order in which they appear in the archive.
+type node struct {
+	mode      os.FileMode // Permissions, 0 if the archive doesn't have an entry for the directory
+	modTime   *time.Time  // Modification time, nil if the archive doesn't have an entry for the directory
+	dirs      map[string]*node
+	dirNames  []string
+	files     map[string]*pkgFiles.File
+	fileNames []string
+}
+
+func newNode() *node {
+	return &node{
+		dirs:      make(map[string]*node),
+		dirNames:  make([]string, 0, pkg.SliceSmallCapacity),
+		files:     make(map[string]*pkgFiles.File),
+		fileNames: make([]string, 0, pkg.SliceSmallCapacity),
+	}
+}
+
+// getDir returns the node of the path provided, creating it and its parents if they don't exist.
+// Files with the same path than any of the directories are replaced.
+func (n *node) getDir(path string) *node {
+	for _, name := range strings.Split(path, "/") {
+		child, ok := n.dirs[name]
+		if !ok {
+			n.removeFile(name)
+			child = newNode()
+			n.dirs[name] = child
+			n.dirNames = append(n.dirNames, name)
+		}
+		n = child
+	}
+	return n
+}
+
+// getFile returns the file of the path provided, or nil if it doesn't exist.
+func (n *node) getFile(path string) *pkgFiles.File {
+	dirPath, name := splitPath(path)
+	for _, dirName := range strings.Split(dirPath, "/") {
+		if dirName == "" {
+			continue
+		}
+		if n = n.dirs[dirName]; n == nil {
+			return nil
+		}
+	}
+	return n.files[name]
+}
+
+// addFile adds the file provided in the path provided, creating its parents if they don't exist.
+// Any file or directory with the same path is replaced.
+func (n *node) addFile(path string, f *pkgFiles.File) {
+	dirPath, name := splitPath(path)
+	if dirPath != "" {
+		n = n.getDir(dirPath)
+	}
+	f.Name = name
+
+	if _, ok := n.dirs[name]; ok {
+		delete(n.dirs, name)
+		n.dirNames = removeName(n.dirNames, name)
+	}
+	if _, ok := n.files[name]; !ok {
+		n.fileNames = append(n.fileNames, name)
+	}
+	n.files[name] = f
+}
+
+// removeFile removes the file with the name provided from the node, if it exists.
+func (n *node) removeFile(name string) {
+	if _, ok := n.files[name]; ok {
+		delete(n.files, name)
+		n.fileNames = removeName(n.fileNames, name)
+	}
+}
+
+// toDir returns the files.Dir that the node represents, with the name provided.
+func (n *node) toDir(name string) pkgFiles.Dir {
+	d := pkgFiles.Dir{
+		Name:    name,
+		Mode:    n.mode,
+		ModTime: n.modTime,
+		Dirs:    make([]pkgFiles.Dir, 0, len(n.dirNames)),
+		Files:   make([]*pkgFiles.File, 0, len(n.fileNames)),
+	}
+	for _, dirName := range n.dirNames {
+		d.Dirs = append(d.Dirs, n.dirs[dirName].toDir(dirName))
+	}
+	for _, fileName := range n.fileNames {
+		d.Files = append(d.Files, n.files[fileName])
+	}
+	return d
+}
+
+// splitPath splits a path separated by '/' into its parent and its base name.
+func splitPath(path string) (dir, name string) {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+// removeName returns the slice of names provided without the name provided.
+func removeName(names []string, name string) []string {
+	for i := range names {
+		if names[i] == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+	return names
+}
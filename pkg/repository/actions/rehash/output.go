@@ -0,0 +1,42 @@
+package rehash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of rehashing a repository.
+type Report struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Objects   int      `json:"objects"`
+	Snapshots int      `json:"snapshots"`
+	Corrupted []string `json:"corrupted"` // Objects that failed the verification after the rehash
+	Unchecked []string `json:"unchecked"` // Objects that couldn't be verified after the rehash
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	_, _ = fmt.Fprintf(buf, "Repository rehashed from %s to %s\nObjects rehashed: %d\nSnapshots rewritten: %d\n",
+		report.From, report.To, report.Objects, report.Snapshots)
+	for _, path := range report.Corrupted {
+		_, _ = fmt.Fprintf(buf, "- corrupted: %s\n", path)
+	}
+	_, _ = fmt.Fprintf(buf, "Corrupted objects: %d\n", len(report.Corrupted))
+	for _, path := range report.Unchecked {
+		_, _ = fmt.Fprintf(buf, "- cannot check: %s\n", path)
+	}
+	if len(report.Unchecked) != 0 {
+		_, _ = fmt.Fprintf(buf, "Objects that couldn't be checked: %d\n", len(report.Unchecked))
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
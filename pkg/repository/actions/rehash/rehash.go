@@ -0,0 +1,315 @@
+package rehash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/check"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/threadSafe"
+	"golang.org/x/sync/errgroup"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// checkpointInterval is the number of objects hashed between every save of the state.
+const checkpointInterval = 1000
+
+// Rehash takes the repo path and changes the hash algorithm of that repo to the one provided.
+// It renames all the objects with their new hashes, rewrites the hashes of all the snapshots and updates the settings.
+// The progress is saved in the rehash folder of the repo, so if it's interrupted, calling it again will resume it.
+// Once it's finished, the integrity of the repo is verified with the new algorithm.
+// It writes a report of the rehash in the writer provided in an human-readable way or in JSON depending
+// of the bool provided.
+// It stops and returns the error of the context provided when it's cancelled, keeping the progress made.
+func Rehash(ctx context.Context, path, hashAlgorithm string, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	opts = opts.Normalize()
+
+	to, err := hasher.GetAlgorithm(hashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	// Get state
+	st, err := readState(path)
+	if err != nil {
+		return err
+	}
+	if st != nil && st.To != to.Name {
+		return fmt.Errorf("there's a rehash to %s in progress", st.To)
+	}
+	if st == nil {
+		sett, err := settings.Read(filepath.Join(path, settings.FileName))
+		if err != nil {
+			return fmt.Errorf("error reading settings: %w", err)
+		}
+		if strings.EqualFold(sett.HashAlgorithm, to.Name) {
+			return fmt.Errorf("repository already uses %s", to.Name)
+		}
+
+		st = &state{
+			From:    strings.ToLower(sett.HashAlgorithm),
+			To:      to.Name,
+			Phase:   phaseHash,
+			Objects: make(map[string]string, 10000),
+		}
+		if err := st.write(path); err != nil {
+			return err
+		}
+	}
+
+	// Do the pending phases
+	report := &Report{From: st.From, To: st.To}
+	if st.Phase == phaseHash {
+		if err := hashObjects(ctx, path, st, opts); err != nil {
+			if ctx.Err() != nil {
+				_ = st.write(path) // Save the progress to resume it later
+			}
+			return fmt.Errorf("error hashing objects: %w", err)
+		}
+		st.Phase = phaseSnapshots
+		if err := st.write(path); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if st.Phase == phaseSnapshots {
+		if err := rewriteSnapshots(path, st); err != nil {
+			return fmt.Errorf("error rewriting snapshots: %w", err)
+		}
+		st.Phase = phaseCommit
+		if err := st.write(path); err != nil {
+			return err
+		}
+	}
+	if report.Snapshots, err = commit(path, st); err != nil {
+		return fmt.Errorf("error committing rehash: %w", err)
+	}
+	report.Objects = len(st.Objects)
+
+	// Verify
+	corrupted, failures, err := check.FindCorrupted(ctx, path, opts)
+	if err != nil {
+		return fmt.Errorf("error verifying repository: %w", err)
+	}
+	report.Corrupted = corrupted
+	report.Unchecked = make([]string, 0, len(failures))
+	for _, f := range failures {
+		report.Unchecked = append(report.Unchecked, f.Path)
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	if len(report.Corrupted) != 0 {
+		return errors.New("corrupted objects found after rehashing")
+	}
+	if len(report.Unchecked) != 0 {
+		return fmt.Errorf("cannot verify %s after rehashing: %w", failures[0].Path, failures[0].Err)
+	}
+	return nil
+}
+
+// hashObjects hashes concurrently all the objects of the repo that are not in the state yet with the new algorithm,
+// verifying them with the old one, and saves their new names in the state.
+// It uses as many workers as threads the normalized options provided have, and stops when the context provided
+// is cancelled.
+func hashObjects(ctx context.Context, repoPath string, st *state, opts *pkg.Options) error {
+	objList, err := files.List(repoPath)
+	if err != nil {
+		return err
+	}
+	pending := make([]string, 0, len(objList))
+	for _, objPath := range objList {
+		if _, done := st.Objects[filepath.Base(objPath)]; !done {
+			pending = append(pending, objPath)
+		}
+	}
+	safeList := threadSafe.NewStringList(pending)
+
+	var mutex sync.Mutex
+	processed := 0
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i := 0; i < opts.NumberOfThreads; i++ {
+		eg.Go(func() error {
+			fromHash, err := hasher.NewHash(st.From)
+			if err != nil {
+				return err
+			}
+			toHash, err := hasher.NewHash(st.To)
+			if err != nil {
+				return err
+			}
+			buf := make([]byte, opts.BufferSize)
+
+			for egCtx.Err() == nil {
+				objPath := safeList.Next()
+				if objPath == nil {
+					break
+				}
+
+				newName, err := hashObject(*objPath, fromHash, toHash, buf)
+				if err != nil {
+					return err
+				}
+				if newName == "" {
+					continue
+				}
+
+				mutex.Lock()
+				st.Objects[filepath.Base(*objPath)] = newName
+				processed++
+				if processed%checkpointInterval == 0 {
+					err = st.write(repoPath)
+				}
+				mutex.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// hashObject hashes the object of the path provided with both hashes provided, verifies it with the first one,
+// and returns its name for the second one. It returns an empty name if the object doesn't have a valid name.
+func hashObject(path string, fromHash, toHash hash.Hash, buf []byte) (string, error) {
+	expectedHash, expectedSize, err := files.GetDataFromName(filepath.Base(path))
+	if err != nil {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open object: %w", err)
+	}
+	defer f.Close()
+
+	fromHash.Reset()
+	toHash.Reset()
+	size, err := io.CopyBuffer(io.MultiWriter(fromHash, toHash), f, buf)
+	if err != nil {
+		return "", fmt.Errorf("error hashing object %s: %w", path, err)
+	}
+	if size != expectedSize {
+		return "", fmt.Errorf("sizes don't match in file %s", path)
+	}
+	if !bytes.Equal(fromHash.Sum(nil), expectedHash) {
+		return "", fmt.Errorf("hashes don't match in file %s", path)
+	}
+	return files.GetName(toHash.Sum(nil), size), nil
+}
+
+// rewriteSnapshots writes all the snapshots of the repo with the new hashes in the rehash folder.
+func rewriteSnapshots(repoPath string, st *state) error {
+	ids, err := snapshots.List(repoPath)
+	if err != nil {
+		return err
+	}
+
+	stagingPath := filepath.Join(repoPath, repository.RehashFolderName)
+	for _, id := range ids {
+		snap, err := snapshots.Read(id.Path(repoPath))
+		if err != nil {
+			return err
+		}
+
+		if err := snap.Walk(func(filePath string, f *pkgFiles.File) error {
+			newName, ok := st.Objects[files.GetObjectName(f)]
+			if !ok {
+				return fmt.Errorf("object of %s in snapshot %s not found", filePath, id)
+			}
+			f.Hash, _, err = files.GetDataFromName(newName)
+			f.Collision = 0
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if err := snapshots.Write(id.Path(stagingPath), snap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit renames the objects of the repo to their new names, replaces the snapshots with the ones rewritten
+// in the rehash folder, updates the settings and removes the rehash folder.
+// It returns the number of snapshots replaced.
+func commit(repoPath string, st *state) (int, error) {
+	filesFolderPath := filepath.Join(repoPath, repository.FilesFolderName)
+	for oldName, newName := range st.Objects {
+		oldPath := filepath.Join(filesFolderPath, oldName[:2], oldName)
+		newPath := filepath.Join(filesFolderPath, newName[:2], newName)
+		if oldPath == newPath {
+			continue
+		}
+		if _, err := os.Stat(oldPath); os.IsNotExist(err) {
+			continue // Already renamed
+		}
+		if err := os.MkdirAll(filepath.Dir(newPath), pkg.DefaultDirPerm); err != nil {
+			return 0, err
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return 0, &os.PathError{
+				Op:   "rename object",
+				Path: oldPath,
+				Err:  err,
+			}
+		}
+	}
+
+	stagingPath := filepath.Join(repoPath, repository.RehashFolderName)
+	ids, err := snapshots.List(stagingPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := os.Rename(id.Path(stagingPath), id.Path(repoPath)); err != nil {
+			return 0, &os.PathError{
+				Op:   "replace snapshot",
+				Path: id.Path(repoPath),
+				Err:  err,
+			}
+		}
+	}
+
+	if err := settings.Write(filepath.Join(repoPath, settings.FileName), st.To); err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return 0, &os.PathError{
+			Op:   "remove rehash folder",
+			Path: stagingPath,
+			Err:  err,
+		}
+	}
+	return len(ids), nil
+}
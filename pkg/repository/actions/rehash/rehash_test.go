@@ -0,0 +1,176 @@
+package rehash_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/rehash"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"golang.org/x/crypto/sha3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestRehash")
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestRehash(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	id := createRepo(t)
+
+	output := bytes.NewBuffer(nil)
+	if err := rehash.Rehash(context.Background(), testingPath, "sha3-256", nil, true, output); err != nil {
+		t.Fatalf("error rehashing repo: %s", err)
+	}
+
+	var report rehash.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if report.From != "sha256" || report.To != "sha3-256" || report.Objects != 3 || report.Snapshots != 1 || len(report.Corrupted) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	checkRehashed(id, t)
+
+	if err := rehash.Rehash(context.Background(), testingPath, "sha3-256", nil, false, ioutil.Discard); err == nil {
+		t.Error("rehashing to the same algorithm must fail")
+	}
+}
+
+func TestRehash_Resume(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	id := createRepo(t)
+
+	// Simulate a rehash interrupted after hashing one object
+	content := []byte(strings.Repeat("a", 10))
+	oldHash := sha256.Sum256(content)
+	newHash := sha3.Sum256(content)
+	state, _ := json.Marshal(map[string]interface{}{
+		"from":    "sha256",
+		"to":      "sha3-256",
+		"phase":   0,
+		"objects": map[string]string{files.GetName(oldHash[:], 10): files.GetName(newHash[:], 10)},
+	})
+	_ = os.Mkdir(filepath.Join(testingPath, repository.RehashFolderName), 0777)
+	_ = ioutil.WriteFile(filepath.Join(testingPath, repository.RehashFolderName, "state.json"), state, 0666)
+
+	if err := rehash.Rehash(context.Background(), testingPath, "blake3", nil, false, ioutil.Discard); err == nil {
+		t.Error("rehashing to another algorithm while a rehash is in progress must fail")
+	}
+	if err := rehash.Rehash(context.Background(), testingPath, "sha3-256", nil, false, ioutil.Discard); err != nil {
+		t.Fatalf("error resuming rehash: %s", err)
+	}
+	checkRehashed(id, t)
+}
+
+func TestRehash_Cancelled(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	id := createRepo(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rehash.Rehash(ctx, testingPath, "sha3-256", nil, false, ioutil.Discard); err == nil {
+		t.Fatal("not error rehashing with context cancelled")
+	}
+	if _, err := os.Stat(filepath.Join(testingPath, repository.RehashFolderName, "state.json")); err != nil {
+		t.Fatalf("state not saved after cancelling: %s", err)
+	}
+
+	if err := rehash.Rehash(context.Background(), testingPath, "sha3-256", nil, false, ioutil.Discard); err != nil {
+		t.Fatalf("error resuming rehash: %s", err)
+	}
+	checkRehashed(id, t)
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+// createRepo creates a sha256 repo with a snapshot of 3 files and returns the ID of that snapshot.
+func createRepo(t *testing.T) snapshots.ID {
+	_ = os.RemoveAll(testingPath)
+	if err := create.Create(testingPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	id := snapshots.NewID("mypc", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	_ = snapshots.Write(id.Path(testingPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{getFile("a", 10, "a")},
+		Dirs: []pkgFiles.Dir{{
+			Name:  "dir",
+			Files: []*pkgFiles.File{getFile("b", 20, "b"), getFile("c", 30, "c"), getFile("d", 30, "c")},
+		}},
+	})
+	return id
+}
+
+// getFile adds to the repo an object of the size provided filled with the character provided and returns its files.File.
+func getFile(name string, size int, char string) *pkgFiles.File {
+	content := []byte(strings.Repeat(char, size))
+	hash := sha256.Sum256(content)
+	_ = ioutil.WriteFile(files.GetPath(testingPath, hash[:], int64(size)), content, 0666)
+
+	return &pkgFiles.File{
+		Name: name,
+		Size: int64(size),
+		Hash: hash[:],
+	}
+}
+
+// checkRehashed checks that the repo, the snapshot provided and its objects use sha3-256.
+func checkRehashed(id snapshots.ID, t *testing.T) {
+	sett, err := settings.Read(filepath.Join(testingPath, settings.FileName))
+	if err != nil {
+		t.Fatalf("error reading settings: %s", err)
+	}
+	if sett.HashAlgorithm != "sha3-256" {
+		t.Errorf("settings not updated: found %s", sett.HashAlgorithm)
+	}
+
+	if _, err := os.Stat(filepath.Join(testingPath, repository.RehashFolderName)); !os.IsNotExist(err) {
+		t.Error("rehash folder not removed")
+	}
+
+	objList, err := files.List(testingPath)
+	if err != nil {
+		t.Fatalf("error listing objects: %s", err)
+	}
+	if len(objList) != 3 {
+		t.Errorf("unexpected number of objects: %v", objList)
+	}
+
+	snap, err := snapshots.Read(id.Path(testingPath))
+	if err != nil {
+		t.Fatalf("error reading snapshot: %s", err)
+	}
+	for _, filePath := range []string{"a", "dir/b", "dir/c", "dir/d"} {
+		f := snap.Get(filePath)
+		if f == nil {
+			t.Errorf("file %s not found in snapshot", filePath)
+			continue
+		}
+
+		content, err := ioutil.ReadFile(files.GetPath(testingPath, f.Hash, f.Size))
+		if err != nil {
+			t.Errorf("cannot read object of %s: %s", filePath, err)
+			continue
+		}
+		if hash := sha3.Sum256(content); !bytes.Equal(hash[:], f.Hash) {
+			t.Errorf("hash of %s not updated", filePath)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rehash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Phases of a rehash. Each one can be safely repeated if it was interrupted.
+const (
+	phaseHash      = iota // Objects are hashed with the new algorithm
+	phaseSnapshots        // Snapshots are rewritten with the new hashes in the rehash folder
+	phaseCommit           // Objects are renamed, snapshots are replaced and settings are updated
+)
+
+// stateFileName is the name of the file where the state of a rehash is saved, in the rehash folder.
+const stateFileName = "state.json"
+
+// state represents the progress of a rehash, so it can be resumed if it's interrupted.
+type state struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Phase   int               `json:"phase"`
+	Objects map[string]string `json:"objects"` // New names of the objects by their old name
+}
+
+// readState reads the state of the rehash of the repo provided. It returns nil if there's no rehash in progress.
+func readState(repoPath string) (*state, error) {
+	path := filepath.Join(repoPath, repository.RehashFolderName, stateFileName)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, &os.PathError{
+			Op:   "read rehash state",
+			Path: path,
+			Err:  err,
+		}
+	}
+
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing rehash state: %w", err)
+	}
+	if s.Objects == nil {
+		s.Objects = make(map[string]string)
+	}
+	return &s, nil
+}
+
+// write saves the state in the repo provided. The state is written in a temporary file
+// and then renamed, so an interruption will never leave a partial state.
+func (s *state) write(repoPath string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error serializing rehash state: %w", err)
+	}
+
+	folderPath := filepath.Join(repoPath, repository.RehashFolderName)
+	if err := os.MkdirAll(folderPath, pkg.DefaultDirPerm); err != nil {
+		return &os.PathError{
+			Op:   "create rehash folder",
+			Path: folderPath,
+			Err:  err,
+		}
+	}
+
+	path := filepath.Join(folderPath, stateFileName)
+	if err := ioutil.WriteFile(path+".tmp", data, pkg.DefaultFilePerm); err != nil {
+		return &os.PathError{
+			Op:   "write rehash state",
+			Path: path + ".tmp",
+			Err:  err,
+		}
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return &os.PathError{
+			Op:   "write rehash state",
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package restore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Policy represents what to do with a file of the snapshot that already exists in the destination.
+type Policy string
+
+// Supported conflict policies
+const (
+	ConflictSkip        Policy = "skip"         // Keep the existing file
+	ConflictOverwrite   Policy = "overwrite"    // Replace the existing file
+	ConflictIfNewer     Policy = "if-newer"     // Replace the existing file if it was modified before the one backed up (or the snapshot, if unknown)
+	ConflictIfDifferent Policy = "if-different" // Replace the existing file if its size or hash are different
+	ConflictRename      Policy = "rename"       // Restore the file with another name, keeping the existing one
+)
+
+// Mode represents how a snapshot is restored in a destination that is not empty,
+// and what to do when a restored file doesn't match its hash.
+// The zero value skips the files that already exist, doesn't delete anything and doesn't abort.
+type Mode struct {
+	OnConflict      Policy // Empty means ConflictSkip
+	Delete          bool   // Remove the files and directories of the destination that are not in the snapshot
+	AbortOnMismatch bool   // Stop at the first restored file that doesn't match its hash and size
+}
+
+// ParsePolicy returns the Policy of the name provided.
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case ConflictSkip, ConflictOverwrite, ConflictIfNewer, ConflictIfDifferent, ConflictRename:
+		return p, nil
+	}
+	return "", fmt.Errorf("conflict policy \"%s\" not supported", name)
+}
+
+// normalize returns the mode with its default values set, or an error if it's not valid.
+func (m Mode) normalize() (Mode, error) {
+	if m.OnConflict == "" {
+		m.OnConflict = ConflictSkip
+	}
+	if _, err := ParsePolicy(string(m.OnConflict)); err != nil {
+		return m, err
+	}
+	if m.Delete && m.OnConflict == ConflictRename {
+		return m, errors.New("rename conflict policy cannot be used when deleting extra files")
+	}
+	return m, nil
+}
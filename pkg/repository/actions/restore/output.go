@@ -0,0 +1,43 @@
+package restore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the result of restoring a snapshot.
+type Report struct {
+	Snapshot string   `json:"snapshot"`
+	Files    int      `json:"files"`   // Files restored and verified
+	Bytes    int64    `json:"bytes"`   // Size of the files restored and verified
+	Skipped  int      `json:"skipped"` // Files of the snapshot that already existed and were kept
+	Deleted  int      `json:"deleted"` // Files and directories of the destination deleted because they were not in the snapshot
+	Failed   []string `json:"failed"`  // Files not restored because their content doesn't match the snapshot
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+	_, _ = fmt.Fprintf(buf, "Snapshot %s restored\nFiles restored and verified: %d (%d bytes)\n",
+		report.Snapshot, report.Files, report.Bytes)
+	if report.Skipped != 0 {
+		_, _ = fmt.Fprintf(buf, "Existing files skipped: %d\n", report.Skipped)
+	}
+	if report.Deleted != 0 {
+		_, _ = fmt.Fprintf(buf, "Extra files deleted: %d\n", report.Deleted)
+	}
+	if len(report.Failed) != 0 {
+		_, _ = fmt.Fprintf(buf, "Files that failed the verification: %d\n", len(report.Failed))
+		for _, path := range report.Failed {
+			_, _ = fmt.Fprintf(buf, "    %s\n", path)
+		}
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
@@ -0,0 +1,428 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/settings"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Restore takes the repo path and restores the snapshot that matches the selector provided in the destination
+// provided, creating it if it doesn't exist. If the selector matches several snapshots, the latest one will be
+// restored (see snapshots.Select for the selectors format). The files that already exist in the destination
+// are handled according to the mode provided, that can also make it delete the files that are not in the snapshot.
+// Every file is hashed while it's restored. The ones whose content doesn't match the snapshot are removed
+// and reported, and an error wrapping files.ErrMismatch is returned after writing the report, or as soon as
+// the first one is found if the mode provided aborts on mismatches.
+// It stops and returns the error of the context provided when it's cancelled. The file that was being
+// restored is removed, and the file that was being replaced is kept.
+// It reports its progress to the renderer of the options provided, and writes a report of the restore in the writer
+// provided in an human-readable way or in JSON depending of the bool provided.
+func Restore(ctx context.Context, path, snapshot, destination string, mode Mode, opts *pkg.Options, inJson bool, writeTo io.Writer) error {
+	report, err := Run(ctx, path, snapshot, destination, mode, opts)
+	if report == nil {
+		return err
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write report to writer provided: %w", err)
+	}
+	return err
+}
+
+// Run is like Restore, but it returns the report of the restore instead of writing it.
+// If some files don't match the snapshot, it returns the report along with the error.
+func Run(ctx context.Context, path, snapshot, destination string, mode Mode, opts *pkg.Options) (*Report, error) {
+	opts = opts.Normalize()
+	mode, err := mode.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := snapshots.Find(path, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := snapshots.Read(id.Path(path))
+	if err != nil {
+		return nil, err
+	}
+	if snap.Incomplete {
+		opts.Log.Infof("Snapshot %s is incomplete", id)
+	}
+
+	if err := os.MkdirAll(destination, pkg.DefaultDirPerm); err != nil {
+		return nil, &os.PathError{
+			Op:   "create destination",
+			Path: destination,
+			Err:  err,
+		}
+	}
+
+	root := pkgFiles.Dir{Files: snap.Files, Dirs: snap.Dirs}
+	totalFiles, totalSize := countDir(root)
+	res := &restorer{
+		ctx:      ctx,
+		repoPath: path,
+		snapTime: id.Time,
+		mode:     mode,
+		buf:      make([]byte, opts.BufferSize),
+		opts:     opts,
+		tracker:  progress.NewTracker("restore", opts.Progress),
+		report: &Report{
+			Snapshot: id.String(),
+			Failed:   make([]string, 0, 10),
+		},
+	}
+	sett, err := settings.Read(filepath.Join(path, settings.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+	if res.h, err = hasher.NewHash(sett.HashAlgorithm); err != nil {
+		return nil, err
+	}
+	if mode.OnConflict == ConflictIfDifferent {
+		if res.hasher, err = hasher.New(sett.HashAlgorithm, opts); err != nil {
+			return nil, err
+		}
+	}
+	res.tracker.SetTotal(totalFiles, totalSize)
+
+	opts.Log.Infof("Restoring snapshot %s in %s", id, destination)
+	res.tracker.Start(progress.DefaultInterval)
+	err = res.restoreDir(root, destination)
+	res.tracker.Finish()
+	if errors.Is(err, files.ErrMismatch) {
+		return res.report, fmt.Errorf("restore aborted: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(res.report.Failed) != 0 {
+		return res.report, fmt.Errorf("%d restored files don't match the snapshot: %w", len(res.report.Failed), files.ErrMismatch)
+	}
+	return res.report, nil
+}
+
+// restorer restores the files of a snapshot from a repository.
+type restorer struct {
+	ctx      context.Context
+	repoPath string
+	snapTime time.Time
+	mode     Mode
+	buf      []byte
+	h        hash.Hash      // Hash of the repository, to verify the files restored
+	hasher   *hasher.Hasher // Only for ConflictIfDifferent
+	opts     *pkg.Options
+	tracker  *progress.Tracker
+	report   *Report
+}
+
+// restoreDir restores the content of the files.Dir provided in the path provided, that must be a directory.
+func (res *restorer) restoreDir(d pkgFiles.Dir, destination string) error {
+	if res.mode.Delete {
+		if err := res.deleteExtra(d, destination); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range d.Files {
+		if err := res.ctx.Err(); err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(destination, f.Name)
+		res.tracker.SetCurrentFile(filePath)
+		restored, err := res.restoreFile(f, filePath)
+		res.tracker.Add(1, f.Size)
+		if errors.Is(err, files.ErrMismatch) {
+			res.opts.Log.Errorf("%s doesn't match the snapshot, it was not restored: %s", filePath, err)
+			res.report.Failed = append(res.report.Failed, filePath)
+			if res.mode.AbortOnMismatch {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			if res.ctx.Err() != nil {
+				return res.ctx.Err()
+			}
+			if res.opts.OmitErrors {
+				res.opts.Log.Error(err.Error())
+				continue
+			}
+			return err
+		}
+		if !restored {
+			res.report.Skipped++
+			continue
+		}
+		res.report.Files++
+		res.report.Bytes += f.Size
+	}
+
+	for _, child := range d.Dirs {
+		childPath := filepath.Join(destination, child.Name)
+		res.opts.Log.Debugf("Restoring directory %s", childPath)
+		if err := res.createDir(childPath); err != nil {
+			if res.opts.OmitErrors {
+				res.opts.Log.Error(err.Error())
+				res.tracker.Add(countDir(child))
+				continue
+			}
+			return err
+		}
+		if err := res.restoreDir(child, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExtra removes the files and directories of the destination provided that are not in the files.Dir provided.
+func (res *restorer) deleteExtra(d pkgFiles.Dir, destination string) error {
+	keep := make(map[string]bool, len(d.Files)+len(d.Dirs))
+	for _, f := range d.Files {
+		keep[f.Name] = true
+	}
+	for _, child := range d.Dirs {
+		keep[child.Name] = true
+	}
+
+	list, err := utils.ListDir(destination)
+	if err != nil {
+		return &os.PathError{
+			Op:   "list directory",
+			Path: destination,
+			Err:  err,
+		}
+	}
+	for _, fi := range list {
+		if keep[fi.Name()] {
+			continue
+		}
+		if err := res.ctx.Err(); err != nil {
+			return err
+		}
+
+		extraPath := filepath.Join(destination, fi.Name())
+		res.opts.Log.Debugf("Deleting %s", extraPath)
+		if err := os.RemoveAll(extraPath); err != nil {
+			err = &os.PathError{
+				Op:   "delete extra file",
+				Path: extraPath,
+				Err:  err,
+			}
+			if res.opts.OmitErrors {
+				res.opts.Log.Error(err.Error())
+				continue
+			}
+			return err
+		}
+		res.report.Deleted++
+	}
+	return nil
+}
+
+// createDir creates the directory of the path provided if it doesn't exist. If something that is not
+// a directory exists in that path, it's replaced only when deleting extra files or overwriting.
+func (res *restorer) createDir(path string) error {
+	stat, err := os.Lstat(path)
+	if err == nil && !stat.IsDir() && (res.mode.Delete || res.mode.OnConflict == ConflictOverwrite) {
+		res.opts.Log.Debugf("Replacing %s with a directory", path)
+		if err := os.Remove(path); err != nil {
+			return &os.PathError{
+				Op:   "replace with directory",
+				Path: path,
+				Err:  err,
+			}
+		}
+	}
+
+	if err := os.MkdirAll(path, pkg.DefaultDirPerm); err != nil {
+		return &os.PathError{
+			Op:   "create directory",
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// restoreFile restores the file provided in the path provided according to the conflict policy,
+// returning whether it was restored.
+func (res *restorer) restoreFile(f *pkgFiles.File, filePath string) (bool, error) {
+	stat, err := os.Lstat(filePath)
+	if os.IsNotExist(err) {
+		res.opts.Log.Debugf("Restoring file %s", filePath)
+		return true, res.copyObject(f, filePath)
+	}
+	if err != nil {
+		return false, &os.PathError{
+			Op:   "stat file",
+			Path: filePath,
+			Err:  err,
+		}
+	}
+
+	// Directories and special files are only replaced when deleting extra files
+	if !stat.Mode().IsRegular() {
+		switch {
+		case res.mode.Delete:
+			res.opts.Log.Debugf("Replacing %s", filePath)
+			if err := os.RemoveAll(filePath); err != nil {
+				return false, &os.PathError{
+					Op:   "replace file",
+					Path: filePath,
+					Err:  err,
+				}
+			}
+			return true, res.copyObject(f, filePath)
+		case res.mode.OnConflict == ConflictSkip:
+			res.opts.Log.Debugf("Skipping existing %s", filePath)
+			return false, nil
+		case res.mode.OnConflict == ConflictRename:
+			return true, res.renameFile(f, filePath)
+		}
+		return false, &os.PathError{
+			Op:   "restore file",
+			Path: filePath,
+			Err:  os.ErrExist,
+		}
+	}
+
+	switch res.mode.OnConflict {
+	case ConflictOverwrite:
+	case ConflictIfNewer:
+		modTime := res.snapTime
+		if f.ModTime != nil {
+			modTime = *f.ModTime
+		}
+		if !modTime.After(stat.ModTime()) {
+			res.opts.Log.Debugf("Skipping %s, it's newer than the one of the snapshot", filePath)
+			return false, nil
+		}
+	case ConflictIfDifferent:
+		equal, err := res.isEqual(f, filePath, stat.Size())
+		if err != nil {
+			return false, err
+		}
+		if equal {
+			res.opts.Log.Debugf("Skipping %s, it's equal to the one of the snapshot", filePath)
+			return false, nil
+		}
+	case ConflictRename:
+		return true, res.renameFile(f, filePath)
+	default:
+		res.opts.Log.Debugf("Skipping existing %s", filePath)
+		return false, nil
+	}
+
+	res.opts.Log.Debugf("Replacing file %s", filePath)
+	return true, res.replaceFile(f, filePath)
+}
+
+// isEqual returns whether the existing file of the path and size provided has the same content
+// than the file of the snapshot provided, comparing their sizes and hashes.
+func (res *restorer) isEqual(f *pkgFiles.File, filePath string, size int64) (bool, error) {
+	if size != f.Size {
+		return false, nil
+	}
+	h, err := res.hasher.HashPath(filePath)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(h, f.Hash), nil
+}
+
+// renameFile restores the file provided next to the existing one of the path provided,
+// adding to its name the suffix ".restored-N" with the first N that is free.
+func (res *restorer) renameFile(f *pkgFiles.File, filePath string) error {
+	for i := 1; ; i++ {
+		newPath := fmt.Sprintf("%s.restored-%d", filePath, i)
+		if _, err := os.Lstat(newPath); os.IsNotExist(err) {
+			res.opts.Log.Debugf("Restoring file %s as %s", filePath, newPath)
+			return res.copyObject(f, newPath)
+		}
+	}
+}
+
+// replaceFile replaces the existing file of the path provided with the file provided. The content is restored
+// in a temporary file that is renamed when it's complete, so the existing file is kept if it fails.
+func (res *restorer) replaceFile(f *pkgFiles.File, filePath string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), ".gkup-restore-*")
+	if err != nil {
+		return &os.PathError{
+			Op:   "create temporary file",
+			Path: filepath.Dir(filePath),
+			Err:  err,
+		}
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+
+	if err := os.Chmod(tmpPath, pkg.DefaultFilePerm); err != nil {
+		_ = os.Remove(tmpPath)
+		return &os.PathError{
+			Op:   "chmod temporary file",
+			Path: tmpPath,
+			Err:  err,
+		}
+	}
+	if err := res.copyObject(f, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return &os.PathError{
+			Op:   "replace file",
+			Path: filePath,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// copyObject copies the content of the file provided from the repository to the path provided,
+// verifying its hash and size (see files.CopyObject).
+func (res *restorer) copyObject(f *pkgFiles.File, filePath string) error {
+	return files.CopyObject(res.ctx, files.GetObjectPath(res.repoPath, f), filePath, f.Hash, f.Size, res.h, res.buf)
+}
+
+// countDir returns the number of files and the total size of the files.Dir provided, including its subdirectories.
+func countDir(d pkgFiles.Dir) (n, size int64) {
+	for _, f := range d.Files {
+		n++
+		size += f.Size
+	}
+	for _, child := range d.Dirs {
+		childN, childSize := countDir(child)
+		n += childN
+		size += childSize
+	}
+	return n, size
+}
@@ -0,0 +1,277 @@
+package restore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"github.com/Miguel-Dorta/gkup/internal"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/backup"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/restore"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	opts        = &pkg.Options{BufferSize: 512, NumberOfThreads: 2}
+	testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestRestore")
+	repoPath    = filepath.Join(testingPath, "repo")
+	srcPath     = filepath.Join(testingPath, "src")
+)
+
+var srcFiles = map[string]string{
+	"a":         "first content",
+	"dir/b":     "second content",
+	"dir/c":     "first content",
+	"dir/sub/d": "third content",
+}
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestRestore(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	if err := backup.Backup(context.Background(), repoPath, "test", []string{srcPath}, opts, false, ioutil.Discard); err != nil {
+		t.Fatalf("error backing up files: %s", err)
+	}
+
+	destination := filepath.Join(testingPath, "restored")
+	output := bytes.NewBuffer(nil)
+	if err := restore.Restore(context.Background(), repoPath, "test", destination, restore.Mode{}, opts, true, output); err != nil {
+		t.Fatalf("error restoring snapshot: %s", err)
+	}
+	var report restore.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if report.Files != 4 || report.Bytes != 53 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	checkRestored(filepath.Join(destination, "src"), t)
+
+	// Existing files
+	output.Reset()
+	if err := restore.Restore(context.Background(), repoPath, "test", destination, restore.Mode{}, opts, true, output); err != nil {
+		t.Errorf("error restoring over existing files: %s", err)
+	}
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if report.Files != 0 || report.Skipped != 4 {
+		t.Errorf("unexpected report restoring over existing files: %+v", report)
+	}
+
+	// Not existing snapshot
+	if err := restore.Restore(context.Background(), repoPath, "missing", destination, restore.Mode{}, opts, true, ioutil.Discard); err == nil {
+		t.Error("not error restoring a snapshot that doesn't exist")
+	}
+
+	// Cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := restore.Restore(ctx, repoPath, "test", filepath.Join(testingPath, "cancelled"), restore.Mode{}, opts, true, ioutil.Discard); err == nil {
+		t.Error("not error restoring with context cancelled")
+	}
+}
+
+func TestRestore_Conflicts(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	backedUpTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(srcPath, "a"), backedUpTime, backedUpTime); err != nil {
+		t.Fatalf("error changing modification time: %s", err)
+	}
+	if err := backup.Backup(context.Background(), repoPath, "test", []string{srcPath}, opts, false, ioutil.Discard); err != nil {
+		t.Fatalf("error backing up files: %s", err)
+	}
+	destination := filepath.Join(testingPath, "restored")
+	src := filepath.Join(destination, "src")
+
+	tests := []struct {
+		mode     restore.Mode
+		existing map[string]string
+		modTime  time.Time
+		restored int
+		expected map[string]string
+	}{
+		{ // Skip
+			mode:     restore.Mode{OnConflict: restore.ConflictSkip},
+			existing: map[string]string{"a": "modified"},
+			restored: 3,
+			expected: map[string]string{"a": "modified"},
+		},
+		{ // Overwrite
+			mode:     restore.Mode{OnConflict: restore.ConflictOverwrite},
+			existing: map[string]string{"a": "modified"},
+			restored: 4,
+			expected: map[string]string{"a": "first content"},
+		},
+		{ // If newer, with an existing file newer than the snapshot
+			mode:     restore.Mode{OnConflict: restore.ConflictIfNewer},
+			existing: map[string]string{"a": "modified"},
+			modTime:  time.Now().Add(time.Hour),
+			restored: 3,
+			expected: map[string]string{"a": "modified"},
+		},
+		{ // If newer, with an existing file older than the one backed up
+			mode:     restore.Mode{OnConflict: restore.ConflictIfNewer},
+			existing: map[string]string{"a": "modified"},
+			modTime:  backedUpTime.Add(-time.Hour),
+			restored: 4,
+			expected: map[string]string{"a": "first content"},
+		},
+		{ // If newer, with an existing file older than the snapshot but newer than the one backed up
+			mode:     restore.Mode{OnConflict: restore.ConflictIfNewer},
+			existing: map[string]string{"a": "modified"},
+			modTime:  backedUpTime.Add(time.Hour),
+			restored: 3,
+			expected: map[string]string{"a": "modified"},
+		},
+		{ // If different
+			mode:     restore.Mode{OnConflict: restore.ConflictIfDifferent},
+			existing: map[string]string{"a": "modified", "dir/b": "second content"},
+			restored: 3,
+			expected: map[string]string{"a": "first content", "dir/b": "second content"},
+		},
+		{ // Rename
+			mode:     restore.Mode{OnConflict: restore.ConflictRename},
+			existing: map[string]string{"a": "modified", "a.restored-1": "taken"},
+			restored: 4,
+			expected: map[string]string{"a": "modified", "a.restored-1": "taken", "a.restored-2": "first content"},
+		},
+		{ // Delete
+			mode:     restore.Mode{OnConflict: restore.ConflictOverwrite, Delete: true},
+			existing: map[string]string{"a": "modified", "extra": "extra", "dir/sub/extra": "extra", "dir/c/x": "dir instead of file"},
+			restored: 4,
+			expected: map[string]string{"a": "first content", "dir/c": "first content", "extra": "", "dir/sub/extra": ""},
+		},
+	}
+
+	for i, test := range tests {
+		if err := os.RemoveAll(destination); err != nil {
+			t.Fatalf("error removing destination: %s", err)
+		}
+		for path, content := range test.existing {
+			writeFile(filepath.Join(src, filepath.FromSlash(path)), content, t)
+			if !test.modTime.IsZero() {
+				if err := os.Chtimes(filepath.Join(src, filepath.FromSlash(path)), test.modTime, test.modTime); err != nil {
+					t.Fatalf("error changing times of %s: %s", path, err)
+				}
+			}
+		}
+
+		report, err := restore.Run(context.Background(), repoPath, "test", destination, test.mode, opts)
+		if err != nil {
+			t.Errorf("[%d] error restoring snapshot: %s", i, err)
+			continue
+		}
+		if report.Files != test.restored {
+			t.Errorf("[%d] unexpected number of files restored\n-> Expected: %d\n-> Found: %d", i, test.restored, report.Files)
+		}
+		for path, content := range test.expected {
+			data, err := ioutil.ReadFile(filepath.Join(src, filepath.FromSlash(path)))
+			if content == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("[%d] %s was not deleted", i, path)
+				}
+				continue
+			}
+			if err != nil || string(data) != content {
+				t.Errorf("[%d] unexpected content of %s\n-> Expected: %s\n-> Found: %s (%v)", i, path, content, string(data), err)
+			}
+		}
+	}
+
+	// Rename with delete
+	if _, err := restore.Run(context.Background(), repoPath, "test", destination, restore.Mode{OnConflict: restore.ConflictRename, Delete: true}, opts); err == nil {
+		t.Error("not error restoring with rename and delete")
+	}
+}
+
+func TestRestore_Corrupted(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	createSource(t)
+	if err := create.Create(repoPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+	if err := backup.Backup(context.Background(), repoPath, "test", []string{srcPath}, opts, false, ioutil.Discard); err != nil {
+		t.Fatalf("error backing up files: %s", err)
+	}
+
+	// Corrupt the object of dir/b
+	content := []byte(srcFiles["dir/b"])
+	hash := sha256.Sum256(content)
+	if err := ioutil.WriteFile(files.GetPath(repoPath, hash[:], int64(len(content))), []byte("corrupted data"), 0644); err != nil {
+		t.Fatalf("error corrupting object: %s", err)
+	}
+
+	destination := filepath.Join(testingPath, "restored")
+	report, err := restore.Run(context.Background(), repoPath, "test", destination, restore.Mode{}, opts)
+	if !errors.Is(err, files.ErrMismatch) {
+		t.Fatalf("unexpected error restoring corrupted snapshot\n-> Expected: %s\n-> Found: %v", files.ErrMismatch, err)
+	}
+	if report == nil || report.Files != 3 || len(report.Failed) != 1 || report.Failed[0] != filepath.Join(destination, "src", "dir", "b") {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(report.Failed[0]); !os.IsNotExist(err) {
+		t.Errorf("corrupted file %s was restored", report.Failed[0])
+	}
+
+	// Abort
+	destination = filepath.Join(testingPath, "aborted")
+	report, err = restore.Run(context.Background(), repoPath, "test", destination, restore.Mode{AbortOnMismatch: true}, opts)
+	if !errors.Is(err, files.ErrMismatch) {
+		t.Fatalf("unexpected error restoring corrupted snapshot\n-> Expected: %s\n-> Found: %v", files.ErrMismatch, err)
+	}
+	if report == nil || len(report.Failed) != 1 || report.Files == 3 {
+		t.Errorf("unexpected report aborting: %+v", report)
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+func createSource(t *testing.T) {
+	for path, content := range srcFiles {
+		writeFile(filepath.Join(srcPath, filepath.FromSlash(path)), content, t)
+	}
+}
+
+func writeFile(path, content string, t *testing.T) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("error creating directory %s: %s", filepath.Dir(path), err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing %s: %s", path, err)
+	}
+}
+
+func checkRestored(path string, t *testing.T) {
+	for filePath, content := range srcFiles {
+		filePath = filepath.Join(path, filepath.FromSlash(filePath))
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Errorf("error reading restored file %s: %s", filePath, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("content of %s doesn't match\n-> Expected: %s\n-> Found: %s", filePath, content, string(data))
+		}
+	}
+}
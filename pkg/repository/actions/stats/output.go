@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report represents the statistics of a repository.
+// Sizes are in bytes.
+type Report struct {
+	Objects        int             `json:"objects"`         // Objects stored in the repository
+	ObjectsSize    int64           `json:"objects_size"`    // Size of the objects stored in the repository
+	LogicalSize    int64           `json:"logical_size"`    // Size of all the files of the snapshots
+	ReferencedSize int64           `json:"referenced_size"` // Size of the objects referenced by the snapshots
+	DedupRatio     float64         `json:"dedup_ratio"`     // LogicalSize / ReferencedSize
+	Snapshots      []SnapshotStats `json:"snapshots"`
+	LargestFiles   []LargeFile     `json:"largest_files"`
+}
+
+// SnapshotStats represents the statistics of a snapshot.
+type SnapshotStats struct {
+	Snapshot    string `json:"snapshot"`
+	Files       int    `json:"files"`
+	LogicalSize int64  `json:"logical_size"` // Size of all the files of the snapshot
+	AddedSize   int64  `json:"added_size"`   // Size of the objects not referenced by any previous snapshot
+}
+
+// LargeFile represents a file of a snapshot. If the same object is referenced by several files,
+// only the first one found is reported.
+type LargeFile struct {
+	Snapshot string `json:"snapshot"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+}
+
+// getTXT returns a easily-readable representation of the report provided.
+func getTXT(report *Report) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 100))
+
+	_, _ = fmt.Fprintf(buf, "Objects in repository: %d (%s)\n", report.Objects, formatSize(report.ObjectsSize))
+	_, _ = fmt.Fprintf(buf, "Snapshots: %d\n", len(report.Snapshots))
+	_, _ = fmt.Fprintf(buf, "Logical size: %s\n", formatSize(report.LogicalSize))
+	_, _ = fmt.Fprintf(buf, "Referenced objects size: %s\n", formatSize(report.ReferencedSize))
+	_, _ = fmt.Fprintf(buf, "Deduplication ratio: %.2f\n", report.DedupRatio)
+
+	if len(report.Snapshots) != 0 {
+		_, _ = buf.WriteString("\nSnapshots\n")
+		for _, snap := range report.Snapshots {
+			_, _ = fmt.Fprintf(buf, "- %s: %d files, %s (added %s)\n",
+				snap.Snapshot, snap.Files, formatSize(snap.LogicalSize), formatSize(snap.AddedSize))
+		}
+	}
+
+	if len(report.LargestFiles) != 0 {
+		_, _ = buf.WriteString("\nLargest files\n")
+		for _, f := range report.LargestFiles {
+			_, _ = fmt.Fprintf(buf, "- %s: %s (%s)\n", f.Snapshot, f.Path, formatSize(f.Size))
+		}
+	}
+	return buf.Bytes()
+}
+
+// getJSON returns the JSON representation of the report provided.
+func getJSON(report *Report) []byte {
+	data, _ := json.Marshal(report)
+	return append(data, '\n')
+}
+
+// formatSize returns an easily-readable representation of the size in bytes provided.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
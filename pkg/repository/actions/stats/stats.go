@@ -0,0 +1,128 @@
+package stats
+
+import (
+	"fmt"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// Stats takes the repo path, reads the snapshots that match the selectors provided (see snapshots.Select)
+// and writes statistics about them and the objects stored in the repo in the writer provided
+// in an human-readable way or in JSON depending of the bool provided.
+// The number of largest files reported is limited to the number provided.
+func Stats(path string, selectors []string, largest int, inJson bool, writeTo io.Writer) error {
+	report, err := GetReport(path, selectors, largest)
+	if err != nil {
+		return err
+	}
+
+	// Get data formatted
+	var output []byte
+	if inJson {
+		output = getJSON(report)
+	} else {
+		output = getTXT(report)
+	}
+
+	// Write output
+	if _, err := writeTo.Write(output); err != nil {
+		return fmt.Errorf("cannot write stats to writer provided: %w", err)
+	}
+	return nil
+}
+
+// GetReport takes the repo path and returns statistics about the snapshots that match the selectors provided
+// (see snapshots.Select) and the objects stored in the repo.
+// The number of largest files reported is limited to the number provided, that must not be negative.
+func GetReport(path string, selectors []string, largest int) (*Report, error) {
+	if largest < 0 {
+		return nil, fmt.Errorf("invalid number of largest files: %d", largest)
+	}
+	report := &Report{
+		Snapshots:    make([]SnapshotStats, 0, 100),
+		LargestFiles: make([]LargeFile, 0, largest),
+	}
+
+	// Get objects stats
+	objList, err := files.List(path)
+	if err != nil {
+		return nil, fmt.Errorf("error listing repository files: %w", err)
+	}
+	for _, objPath := range objList {
+		_, size, err := files.GetDataFromName(filepath.Base(objPath))
+		if err != nil {
+			continue
+		}
+		report.Objects++
+		report.ObjectsSize += size
+	}
+
+	// Get snapshots sorted by time
+	ids, err := snapshots.List(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get snapshots: %w", err)
+	}
+	if ids, err = snapshots.Select(ids, selectors); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return ids[i].Time.Before(ids[j].Time)
+	})
+
+	// Get snapshots stats
+	referenced := make(map[string]bool, len(objList))
+	largestFiles := make(map[string]LargeFile, 1000)
+	for _, id := range ids {
+		snap, err := snapshots.Read(id.Path(path))
+		if err != nil {
+			return nil, err
+		}
+
+		snapStats := SnapshotStats{Snapshot: id.String()}
+		_ = snap.Walk(func(filePath string, f *pkgFiles.File) error {
+			name := files.GetObjectName(f)
+			snapStats.Files++
+			snapStats.LogicalSize += f.Size
+			if !referenced[name] {
+				referenced[name] = true
+				snapStats.AddedSize += f.Size
+				largestFiles[name] = LargeFile{
+					Snapshot: id.String(),
+					Path:     filePath,
+					Size:     f.Size,
+				}
+			}
+			return nil
+		})
+
+		report.Snapshots = append(report.Snapshots, snapStats)
+		report.LogicalSize += snapStats.LogicalSize
+		report.ReferencedSize += snapStats.AddedSize
+	}
+	if report.ReferencedSize != 0 {
+		report.DedupRatio = float64(report.LogicalSize) / float64(report.ReferencedSize)
+	}
+
+	// Get largest files
+	for _, f := range largestFiles {
+		report.LargestFiles = append(report.LargestFiles, f)
+	}
+	sort.Slice(report.LargestFiles, func(i, j int) bool {
+		fi, fj := report.LargestFiles[i], report.LargestFiles[j]
+		if fi.Size != fj.Size {
+			return fi.Size > fj.Size
+		}
+		if fi.Snapshot != fj.Snapshot {
+			return fi.Snapshot < fj.Snapshot
+		}
+		return fi.Path < fj.Path
+	})
+	if len(report.LargestFiles) > largest {
+		report.LargestFiles = report.LargestFiles[:largest]
+	}
+	return report, nil
+}
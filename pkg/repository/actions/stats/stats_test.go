@@ -0,0 +1,122 @@
+package stats_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/Miguel-Dorta/gkup/internal"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/create"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/actions/stats"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testingPath = filepath.Join(os.TempDir(), "gkup_pkg_repository_TestStats")
+
+func init() {
+	internal.Version = "v1.0.0"
+}
+
+func TestStats(t *testing.T) {
+	defer os.RemoveAll(testingPath)
+	if err := create.Create(testingPath, "sha256"); err != nil {
+		t.Fatalf("error creating repo: %s", err)
+	}
+
+	getFile("orphan", 5, "e")
+	writeSnapshot(snapshots.NewID("", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		getFile("a", 10, "a"), getFile("b", 20, "b"))
+	writeSnapshot(snapshots.NewID("mypc", time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)),
+		getFile("a", 10, "a"), getFile("c", 30, "c"), getFile("d", 30, "c"))
+
+	// All snapshots
+	expected := stats.Report{
+		Objects:        4,
+		ObjectsSize:    65,
+		LogicalSize:    100,
+		ReferencedSize: 60,
+		DedupRatio:     100.0 / 60.0,
+		Snapshots: []stats.SnapshotStats{
+			{Snapshot: "2019-01-01_00-00-00", Files: 2, LogicalSize: 30, AddedSize: 30},
+			{Snapshot: "mypc/2019-01-02_00-00-00", Files: 3, LogicalSize: 70, AddedSize: 30},
+		},
+		LargestFiles: []stats.LargeFile{
+			{Snapshot: "mypc/2019-01-02_00-00-00", Path: "dir/c", Size: 30},
+			{Snapshot: "2019-01-01_00-00-00", Path: "dir/b", Size: 20},
+		},
+	}
+	checkStats(nil, expected, t)
+
+	// Selected snapshots
+	expected = stats.Report{
+		Objects:        4,
+		ObjectsSize:    65,
+		LogicalSize:    70,
+		ReferencedSize: 40,
+		DedupRatio:     70.0 / 40.0,
+		Snapshots: []stats.SnapshotStats{
+			{Snapshot: "mypc/2019-01-02_00-00-00", Files: 3, LogicalSize: 70, AddedSize: 40},
+		},
+		LargestFiles: []stats.LargeFile{
+			{Snapshot: "mypc/2019-01-02_00-00-00", Path: "dir/c", Size: 30},
+			{Snapshot: "mypc/2019-01-02_00-00-00", Path: "a", Size: 10},
+		},
+	}
+	checkStats([]string{"mypc"}, expected, t)
+
+	// Invalid number of largest files
+	if err := stats.Stats(testingPath, nil, -1, true, ioutil.Discard); err == nil {
+		t.Error("not error getting stats with a negative number of largest files")
+	}
+}
+
+//////////////////////////
+//// HELPER FUNCTIONS ////
+//////////////////////////
+
+// getFile adds to the repo an object of the size provided filled with the character provided and returns its files.File.
+func getFile(name string, size int, char string) *pkgFiles.File {
+	content := []byte(strings.Repeat(char, size))
+	hash := sha256.Sum256(content)
+	_ = ioutil.WriteFile(files.GetPath(testingPath, hash[:], int64(size)), content, 0666)
+
+	return &pkgFiles.File{
+		Name: name,
+		Size: int64(size),
+		Hash: hash[:],
+	}
+}
+
+func writeSnapshot(id snapshots.ID, root *pkgFiles.File, dirFiles ...*pkgFiles.File) {
+	_ = snapshots.Write(id.Path(testingPath), &snapshots.Snapshot{
+		Files: []*pkgFiles.File{root},
+		Dirs:  []pkgFiles.Dir{{Name: "dir", Files: dirFiles}},
+	})
+}
+
+func checkStats(selectors []string, expected stats.Report, t *testing.T) {
+	output := bytes.NewBuffer(nil)
+	if err := stats.Stats(testingPath, selectors, 2, true, output); err != nil {
+		t.Fatalf("error getting stats of %v: %s", selectors, err)
+	}
+
+	var report stats.Report
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("cannot unmarshal report %s: %s", output.String(), err)
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("stats of %v don't match the expected result\n-> Expected: %+v\n-> Found: %+v", selectors, expected, report)
+	}
+
+	if err := stats.Stats(testingPath, selectors, 2, false, ioutil.Discard); err != nil {
+		t.Errorf("error getting stats of %v in TXT: %s", selectors, err)
+	}
+}
@@ -1,6 +1,8 @@
 package repository
 
 const (
-	FilesFolderName     = "files"
-	SnapshotsFolderName = "snapshots"
+	FilesFolderName      = "files"
+	QuarantineFolderName = "quarantine"
+	RehashFolderName     = "rehash"
+	SnapshotsFolderName  = "snapshots"
 )
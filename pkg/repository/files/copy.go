@@ -0,0 +1,66 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"hash"
+	"io"
+	"os"
+)
+
+// ErrMismatch is the error returned when the content of an object doesn't match the hash and size expected.
+var ErrMismatch = errors.New("content doesn't match the hash and size expected")
+
+// CopyObject copies the object of the path provided to the destination provided, that must not exist,
+// hashing its content with the hash provided while it's written. If the content doesn't match the hash and size
+// provided, the destination is removed and an error wrapping ErrMismatch is returned.
+// The destination is also removed if the copy fails or the context provided is cancelled.
+func CopyObject(ctx context.Context, objPath, destination string, expectedHash []byte, expectedSize int64, h hash.Hash, buf []byte) error {
+	obj, err := os.Open(objPath)
+	if err != nil {
+		return &os.PathError{
+			Op:   "open object",
+			Path: objPath,
+			Err:  err,
+		}
+	}
+	defer obj.Close()
+
+	dst, err := os.Create(destination)
+	if err != nil {
+		return &os.PathError{
+			Op:   "create file",
+			Path: destination,
+			Err:  err,
+		}
+	}
+
+	h.Reset()
+	size, err := io.CopyBuffer(io.MultiWriter(dst, h), utils.NewContextReader(ctx, obj), buf)
+	if err != nil {
+		_ = dst.Close()
+		_ = os.Remove(destination)
+		return fmt.Errorf("error copying object %s to %s: %w", objPath, destination, err)
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(destination)
+		return &os.PathError{
+			Op:   "close file",
+			Path: destination,
+			Err:  err,
+		}
+	}
+
+	if size != expectedSize || !bytes.Equal(h.Sum(nil), expectedHash) {
+		_ = os.Remove(destination)
+		return &os.PathError{
+			Op:   "verify object",
+			Path: objPath,
+			Err:  ErrMismatch,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"os"
+	"path/filepath"
+)
+
+// List returns the paths of all the regular files stored in the files folder of the repository
+// of the path provided.
+func List(path string) ([]string, error) {
+	result := make([]string, 0, 10000)
+
+	filesFolderPath := filepath.Join(path, repository.FilesFolderName)
+	for i:=0; i<=0xff; i++ {
+		dirPath := filepath.Join(filesFolderPath, fmt.Sprintf("%02x", i))
+
+		// List dir
+		fList, err := utils.ListDir(dirPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, &os.PathError{
+				Op:   "list repository files",
+				Path: dirPath,
+				Err:  err,
+			}
+		}
+
+		// Add files to list
+		for _, f := range fList {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			result = append(result, filepath.Join(dirPath, f.Name()))
+		}
+	}
+	return result, nil
+}
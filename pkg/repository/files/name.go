@@ -4,10 +4,15 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+// GetDataFromName returns the hash and the size of the object with the name provided.
+// The collision index that the name may have is ignored.
 func GetDataFromName(name string) (hash []byte, size int64, err error) {
 	// Get index of character '-'
 	separatorIndex := strings.IndexByte(name, '-')
@@ -22,10 +27,43 @@ func GetDataFromName(name string) (hash []byte, size int64, err error) {
 	}
 
 	// Get size
-	size, err = strconv.ParseInt(name[separatorIndex+1:], 10, 64)
+	sizeStr := name[separatorIndex+1:]
+	if collisionIndex := strings.IndexByte(sizeStr, '-'); collisionIndex >= 0 {
+		if collision, err := strconv.Atoi(sizeStr[collisionIndex+1:]); err != nil || collision < 1 {
+			return nil, -1, errors.New("incorrect collision index")
+		}
+		sizeStr = sizeStr[:collisionIndex]
+	}
+	size, err = strconv.ParseInt(sizeStr, 10, 64)
 	if err != nil {
 		return nil, -1, fmt.Errorf("error parsing size from name: %w", err)
 	}
 
 	return hash, size, nil
 }
+
+// GetName returns the name that an object with the hash and size provided must have in the repository.
+func GetName(hash []byte, size int64) string {
+	return fmt.Sprintf("%x-%d", hash, size)
+}
+
+// GetPath returns the path where an object with the hash and size provided must be stored
+// in the repository of the path provided.
+func GetPath(repoPath string, hash []byte, size int64) string {
+	name := GetName(hash, size)
+	return filepath.Join(repoPath, repository.FilesFolderName, name[:2], name)
+}
+
+// GetObjectName returns the name of the object of the file provided, including its collision index if it has one.
+func GetObjectName(f *pkgFiles.File) string {
+	if f.Collision == 0 {
+		return GetName(f.Hash, f.Size)
+	}
+	return fmt.Sprintf("%x-%d-%d", f.Hash, f.Size, f.Collision)
+}
+
+// GetObjectPath returns the path where the object of the file provided is stored in the repository of the path provided.
+func GetObjectPath(repoPath string, f *pkgFiles.File) string {
+	name := GetObjectName(f)
+	return filepath.Join(repoPath, repository.FilesFolderName, name[:2], name)
+}
@@ -0,0 +1,113 @@
+package files
+
+import (
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	pkgFiles "github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// TempObject is an object that is being written to the repository and whose hash is still unknown.
+// Everything written to it is hashed, and it's moved to its final path when committed.
+type TempObject struct {
+	f        *os.File
+	h        hash.Hash
+	size     int64
+	repoPath string
+}
+
+// NewTempObject creates a new TempObject in the repository of the path provided that will be hashed
+// with the hash provided.
+func NewTempObject(repoPath string, h hash.Hash) (*TempObject, error) {
+	f, err := ioutil.TempFile(filepath.Join(repoPath, repository.FilesFolderName), "tmp-*")
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "create temporary object",
+			Path: repoPath,
+			Err:  err,
+		}
+	}
+
+	h.Reset()
+	return &TempObject{
+		f:        f,
+		h:        h,
+		repoPath: repoPath,
+	}, nil
+}
+
+// Write writes the data provided to the object and adds it to its hash.
+func (o *TempObject) Write(p []byte) (int, error) {
+	n, err := o.f.Write(p)
+	o.h.Write(p[:n])
+	o.size += int64(n)
+	return n, err
+}
+
+// Path returns the path of the temporary object.
+func (o *TempObject) Path() string {
+	return o.f.Name()
+}
+
+// Sum returns the hash and the size of the data written to the object so far.
+func (o *TempObject) Sum() (hash []byte, size int64) {
+	return o.h.Sum(nil), o.size
+}
+
+// Commit closes the object and moves it to the path where it must be stored according to its hash and size.
+// If that object already exists in the repository, the temporary one is discarded.
+// It returns the hash and the size of the object.
+func (o *TempObject) Commit() (hash []byte, size int64, err error) {
+	return o.CommitCollision(0)
+}
+
+// CommitCollision is like Commit, but it stores the object with the collision index provided.
+func (o *TempObject) CommitCollision(collision int) (hash []byte, size int64, err error) {
+	if err := o.f.Close(); err != nil {
+		_ = os.Remove(o.f.Name())
+		return nil, -1, fmt.Errorf("error closing temporary object %s: %w", o.f.Name(), err)
+	}
+	hash, size = o.h.Sum(nil), o.size
+	path := GetObjectPath(o.repoPath, &pkgFiles.File{Hash: hash, Size: size, Collision: collision})
+
+	// Discard if it already exists
+	if _, err := os.Stat(path); err == nil {
+		_ = os.Remove(o.f.Name())
+		return hash, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), pkg.DefaultDirPerm); err != nil {
+		_ = os.Remove(o.f.Name())
+		return nil, -1, &os.PathError{
+			Op:   "create files folder",
+			Path: filepath.Dir(path),
+			Err:  err,
+		}
+	}
+	if err := os.Rename(o.f.Name(), path); err != nil {
+		_ = os.Remove(o.f.Name())
+		return nil, -1, &os.PathError{
+			Op:   "commit temporary object",
+			Path: path,
+			Err:  err,
+		}
+	}
+	return hash, size, nil
+}
+
+// Discard closes and removes the object.
+func (o *TempObject) Discard() error {
+	_ = o.f.Close()
+	if err := os.Remove(o.f.Name()); err != nil {
+		return &os.PathError{
+			Op:   "remove temporary object",
+			Path: o.f.Name(),
+			Err:  err,
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Miguel-Dorta/gkup/internal"
 	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/hasher"
 	"github.com/pelletier/go-toml"
 	"io/ioutil"
 	"os"
@@ -38,10 +39,20 @@ func Read(path string) (Settings, error) {
 	if s.Version == "" || s.HashAlgorithm == "" {
 		return Settings{}, errors.New("incomplete information in settings")
 	}
+
+	// Check if the hash algorithm is supported
+	if _, err := hasher.GetAlgorithm(s.HashAlgorithm); err != nil {
+		return Settings{}, fmt.Errorf("invalid settings: %w", err)
+	}
 	return s, nil
 }
 
 func Write(path, hashAlgorithm string) error {
+	// Check if the hash algorithm is supported
+	if _, err := hasher.GetAlgorithm(hashAlgorithm); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+
 	// Serialize settings
 	data, err := toml.Marshal(&Settings{
 		Version:       internal.Version,
@@ -18,6 +18,9 @@ func TestWrite(t *testing.T) {
 	if err := settings.Write(path, "sha256"); err != nil {
 		t.Fatalf("write error in path %s: %s", path, err)
 	}
+	if err := settings.Write(path, "crc32"); err == nil {
+		t.Error("not error writing unknown hash algorithm")
+	}
 }
 
 func TestRead(t *testing.T) {
@@ -39,6 +42,9 @@ func TestRead(t *testing.T) {
 	if sett := checkReadInvalid("testdata/lacks_info.toml"); sett != nil {
 		t.Errorf("not error in testdata/lacks_info.toml: %+v", sett)
 	}
+	if sett := checkReadInvalid("testdata/unknown_algorithm.toml"); sett != nil {
+		t.Errorf("not error in testdata/unknown_algorithm.toml: %+v", sett)
+	}
 	if sett := checkReadInvalid("testdata/non_existing.toml"); sett != nil {
 		t.Errorf("not error in testdata/non_existing.toml: %+v", sett)
 	}
@@ -0,0 +1,214 @@
+package snapshots
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg/repository"
+	"github.com/Miguel-Dorta/gkup/pkg/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeLayout is the layout of the time contained in the name of a snapshot file.
+const timeLayout = "2006-01-02_15-04-05"
+
+// fileExtension is the extension of the snapshot files.
+const fileExtension = ".json"
+
+// ID identifies a snapshot by its name and the time when it was created.
+// Snapshots with no name defined have an empty Name.
+type ID struct {
+	Name string
+	Time time.Time
+}
+
+// NewID returns the ID of a snapshot with the name provided created in the time provided.
+// The time is truncated to seconds and converted to UTC.
+func NewID(name string, t time.Time) ID {
+	return ID{
+		Name: name,
+		Time: t.UTC().Truncate(time.Second),
+	}
+}
+
+// ParseID parses a string with the format returned by ID.String.
+func ParseID(s string) (ID, error) {
+	var name, date string
+	if i := strings.LastIndexByte(s, '/'); i < 0 {
+		date = s
+	} else {
+		name, date = s[:i], s[i+1:]
+	}
+	date = strings.TrimSuffix(date, fileExtension)
+
+	t, err := time.ParseInLocation(timeLayout, date, time.UTC)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid snapshot %s: %w", s, err)
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return ID{}, fmt.Errorf("invalid snapshot %s: invalid name", s)
+	}
+	return ID{Name: name, Time: t}, nil
+}
+
+// String returns the representation of the snapshot ID in the format "[name/]YYYY-MM-DD_hh-mm-ss".
+func (id ID) String() string {
+	if id.Name == "" {
+		return id.Time.Format(timeLayout)
+	}
+	return id.Name + "/" + id.Time.Format(timeLayout)
+}
+
+// Path returns the path of the snapshot file inside the repository of the path provided.
+func (id ID) Path(repoPath string) string {
+	return filepath.Join(repoPath, repository.SnapshotsFolderName, id.Name, id.Time.Format(timeLayout)+fileExtension)
+}
+
+// List returns the IDs of all the snapshots of the repository of the path provided,
+// sorted by name and time.
+func List(repoPath string) ([]ID, error) {
+	snapshotsFolderPath := filepath.Join(repoPath, repository.SnapshotsFolderName)
+
+	// Add snapshots with no name defined
+	ids, err := listFolder(snapshotsFolderPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	fileList, err := utils.ListDir(snapshotsFolderPath)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "list snapshots folder",
+			Path: snapshotsFolderPath,
+			Err:  err,
+		}
+	}
+	// Iterate folders to get snapshots with name
+	for _, f := range fileList {
+		if !f.IsDir() {
+			continue
+		}
+
+		namedIDs, err := listFolder(filepath.Join(snapshotsFolderPath, f.Name()), f.Name())
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, namedIDs...)
+	}
+
+	Sort(ids)
+	return ids, nil
+}
+
+// ListByName returns the IDs of the snapshots with the name provided, sorted by time.
+func ListByName(repoPath, name string) ([]ID, error) {
+	ids, err := listFolder(filepath.Join(repoPath, repository.SnapshotsFolderName, name), name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	Sort(ids)
+	return ids, nil
+}
+
+// Sort sorts the IDs provided by name (ignoring case) and time.
+func Sort(ids []ID) {
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Name != ids[j].Name {
+			iLow, jLow := strings.ToLower(ids[i].Name), strings.ToLower(ids[j].Name)
+			if iLow == jLow {
+				return ids[i].Name < ids[j].Name
+			}
+			return iLow < jLow
+		}
+		return ids[i].Time.Before(ids[j].Time)
+	})
+}
+
+// listFolder returns the IDs of the snapshot files found in the path provided,
+// assigning them the name provided.
+func listFolder(path, name string) ([]ID, error) {
+	fileList, err := utils.ListDir(path)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "list snapshots folder",
+			Path: path,
+			Err:  err,
+		}
+	}
+
+	ids := make([]ID, 0, len(fileList))
+	for _, f := range fileList {
+		if !f.Mode().IsRegular() || !strings.HasSuffix(f.Name(), fileExtension) {
+			continue
+		}
+
+		t, err := time.ParseInLocation(timeLayout, strings.TrimSuffix(f.Name(), fileExtension), time.UTC)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, ID{Name: name, Time: t})
+	}
+	return ids, nil
+}
+
+// Select returns the IDs provided that match any of the selectors provided.
+// A selector can be a snapshot ID, in the format returned by ID.String, or a snapshot name,
+// selecting all the snapshots with that name. If no selectors are provided, all the IDs are returned.
+// It returns an error if a selector doesn't match any ID.
+func Select(ids []ID, selectors []string) ([]ID, error) {
+	if len(selectors) == 0 {
+		return ids, nil
+	}
+
+	selected := make(map[ID]bool, len(ids))
+	for _, selector := range selectors {
+		selectorID, err := ParseID(selector)
+		isID := err == nil
+
+		found := false
+		for _, id := range ids {
+			if (isID && id.Name == selectorID.Name && id.Time.Equal(selectorID.Time)) || (!isID && id.Name == selector) {
+				selected[id] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("snapshot %s not found", selector)
+		}
+	}
+
+	result := make([]ID, 0, len(selected))
+	for _, id := range ids {
+		if selected[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// Find returns the ID of the latest snapshot of the repository of the path provided that matches
+// the selector provided. See Select for the selectors format.
+func Find(repoPath, selector string) (ID, error) {
+	ids, err := List(repoPath)
+	if err != nil {
+		return ID{}, err
+	}
+	if ids, err = Select(ids, []string{selector}); err != nil {
+		return ID{}, err
+	}
+
+	latest := ids[0]
+	for _, id := range ids[1:] {
+		if id.Time.After(latest.Time) {
+			latest = id
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,63 @@
+package snapshots_test
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"testing"
+	"time"
+)
+
+func TestParseID(t *testing.T) {
+	valid := []struct {
+		str      string
+		expected snapshots.ID
+	}{
+		{"2019-12-31_23-59-59", snapshots.ID{Time: time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)}},
+		{"mypc/0001-01-01_00-00-00", snapshots.ID{Name: "mypc", Time: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"mypc/2038-01-19_03-14-08.json", snapshots.ID{Name: "mypc", Time: time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC)}},
+	}
+	invalid := []string{"", "mypc", "mypc/2019-12-31", "my/pc/2019-12-31_23-59-59", "2019-13-31_23-59-59"}
+
+	for _, v := range valid {
+		id, err := snapshots.ParseID(v.str)
+		if err != nil {
+			t.Errorf("error parsing valid ID %s: %s", v.str, err)
+			continue
+		}
+		if id.Name != v.expected.Name || !id.Time.Equal(v.expected.Time) {
+			t.Errorf("unexpected ID parsing %s: %+v", v.str, id)
+		}
+		if id.String()+".json" != v.str && id.String() != v.str {
+			t.Errorf("unexpected string of ID %s: %s", v.str, id.String())
+		}
+	}
+
+	for _, str := range invalid {
+		if _, err := snapshots.ParseID(str); err == nil {
+			t.Errorf("invalid ID \"%s\" was parsed", str)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	ids := []snapshots.ID{
+		snapshots.NewID("", time.Unix(0, 0)),
+		snapshots.NewID("mypc", time.Unix(0, 0)),
+		snapshots.NewID("mypc", time.Unix(1, 0)),
+		snapshots.NewID("server", time.Unix(0, 0)),
+	}
+
+	selected, err := snapshots.Select(ids, []string{"server", "mypc/1970-01-01_00-00-01", "1970-01-01_00-00-00"})
+	if err != nil {
+		t.Fatalf("error selecting IDs: %s", err)
+	}
+	if len(selected) != 3 || selected[0] != ids[0] || selected[1] != ids[2] || selected[2] != ids[3] {
+		t.Errorf("unexpected selection: %+v", selected)
+	}
+
+	if selected, err = snapshots.Select(ids, nil); err != nil || len(selected) != len(ids) {
+		t.Errorf("unexpected selection with no selectors: %+v (error: %v)", selected, err)
+	}
+	if _, err = snapshots.Select(ids, []string{"laptop"}); err == nil {
+		t.Error("not error selecting not existing snapshot")
+	}
+}
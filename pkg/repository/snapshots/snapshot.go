@@ -0,0 +1,117 @@
+package snapshots
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Miguel-Dorta/gkup/pkg"
+	"github.com/Miguel-Dorta/gkup/pkg/files"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot represents the tree of files and directories saved in a snapshot file.
+type Snapshot struct {
+	Version    string        `json:"version"`
+	Incomplete bool          `json:"incomplete,omitempty"` // The operation that created it was interrupted
+	Dirs       []files.Dir   `json:"dirs"`
+	Files      []*files.File `json:"files"`
+}
+
+// WalkFunc is the type of the function called for each file visited by Snapshot.Walk.
+// The path provided is relative to the root of the snapshot and uses '/' as separator.
+type WalkFunc func(path string, f *files.File) error
+
+// Read reads and parses the snapshot file of the path provided.
+func Read(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "read snapshot",
+			Path: path,
+			Err:  err,
+		}
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Write writes the snapshot provided in the path provided, creating its parent directory if needed.
+func Write(path string, s *Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error serializing snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), pkg.DefaultDirPerm); err != nil {
+		return &os.PathError{
+			Op:   "create snapshot folder",
+			Path: filepath.Dir(path),
+			Err:  err,
+		}
+	}
+
+	if err := ioutil.WriteFile(path, data, pkg.DefaultFilePerm); err != nil {
+		return &os.PathError{
+			Op:   "write snapshot",
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// Walk calls the function provided for each file of the snapshot, in the order they are saved.
+// If the function returns an error, the walk stops and that error is returned.
+func (s *Snapshot) Walk(fn WalkFunc) error {
+	return walk(files.Dir{Dirs: s.Dirs, Files: s.Files}, "", fn)
+}
+
+// Get returns the file of the path provided, or nil if it doesn't exist in the snapshot.
+// The path must be relative to the root of the snapshot and use '/' as separator.
+func (s *Snapshot) Get(filePath string) *files.File {
+	parts := strings.Split(strings.Trim(path.Clean("/"+filePath), "/"), "/")
+	d := files.Dir{Dirs: s.Dirs, Files: s.Files}
+
+	for _, dirName := range parts[:len(parts)-1] {
+		found := false
+		for _, child := range d.Dirs {
+			if child.Name == dirName {
+				d = child
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	for _, f := range d.Files {
+		if f.Name == parts[len(parts)-1] {
+			return f
+		}
+	}
+	return nil
+}
+
+// walk calls the function provided for each file of the directory provided and its children.
+func walk(d files.Dir, dirPath string, fn WalkFunc) error {
+	for _, f := range d.Files {
+		if err := fn(path.Join(dirPath, f.Name), f); err != nil {
+			return err
+		}
+	}
+	for _, child := range d.Dirs {
+		if err := walk(child, path.Join(dirPath, child.Name), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package snapshots_test
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"testing"
+)
+
+var snap = &snapshots.Snapshot{
+	Files: []*files.File{{Name: "a"}},
+	Dirs: []files.Dir{
+		{
+			Name:  "dir",
+			Files: []*files.File{{Name: "b"}, {Name: "c"}},
+			Dirs:  []files.Dir{{Name: "subdir", Files: []*files.File{{Name: "d"}}}},
+		},
+		{Name: "empty"},
+	},
+}
+
+func TestSnapshot_Walk(t *testing.T) {
+	expected := []string{"a", "dir/b", "dir/c", "dir/subdir/d"}
+
+	i := 0
+	_ = snap.Walk(func(path string, f *files.File) error {
+		if i >= len(expected) || path != expected[i] {
+			t.Errorf("unexpected path in position %d: %s", i, path)
+		}
+		i++
+		return nil
+	})
+	if i != len(expected) {
+		t.Errorf("unexpected number of files walked: %d", i)
+	}
+}
+
+func TestSnapshot_Get(t *testing.T) {
+	for _, path := range []string{"a", "/a", "dir/b", "dir/./c", "dir/subdir/d", "/dir/subdir/../subdir/d"} {
+		f := snap.Get(path)
+		if f == nil {
+			t.Errorf("file %s not found", path)
+		}
+	}
+
+	for _, path := range []string{"", "b", "dir", "dir/d", "empty/a", "not_exists/a"} {
+		if f := snap.Get(path); f != nil {
+			t.Errorf("unexpected file found in %s: %+v", path, f)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package schedule parses cron-style schedules and computes when they are due.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch is how far in the future Next looks for a time that matches a schedule.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// descriptors are the predefined schedules that can be used instead of the five fields.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field represents the range of values of a field of a schedule.
+type field struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteField = field{"minute", 0, 59}
+	hourField   = field{"hour", 0, 23}
+	domField    = field{"day of month", 1, 31}
+	monthField  = field{"month", 1, 12}
+	dowField    = field{"day of week", 0, 7}
+)
+
+// Schedule represents a cron-style schedule.
+type Schedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64 // Bit sets of the values allowed
+	domAny, dowAny                bool   // Whether the day fields start with "*"
+}
+
+// Parse parses a schedule with the five fields of cron (minute, hour, day of month, month and day of week)
+// separated by spaces, or one of the descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight or @hourly.
+// Each field can be "*", a value, a range ("1-5"), a step ("*/15" or "0-30/10") or a list of them ("1,15,30").
+// Sunday can be 0 or 7. If both day fields are restricted (they don't start with "*"), a day matches
+// if any of them matches, like in cron.
+func Parse(spec string) (*Schedule, error) {
+	expanded := strings.TrimSpace(spec)
+	if d, ok := descriptors[strings.ToLower(expanded)]; ok {
+		expanded = d
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule \"%s\": expected 5 fields, found %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		spec:   spec,
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for i, p := range []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *p.bits, err = parseField(fields[i], p.f); err != nil {
+			return nil, fmt.Errorf("invalid schedule \"%s\": %w", spec, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // Sunday
+	}
+	return s, nil
+}
+
+// String returns the schedule as it was parsed.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after the time provided that matches the schedule, in the location of the time provided.
+// It returns the zero time if there is none in the next five years (e.g. "0 0 30 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay returns whether the day of the time provided matches the day fields of the schedule.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseField returns the bit set of the values allowed by the expression provided for the field provided.
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %s \"%s\"", f.name, part)
+			}
+			rangeExpr, step = part[:i], n
+		}
+
+		var from, to int
+		switch {
+		case rangeExpr == "*":
+			from, to = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			i := strings.IndexByte(rangeExpr, '-')
+			var err1, err2 error
+			from, err1 = strconv.Atoi(rangeExpr[:i])
+			to, err2 = strconv.Atoi(rangeExpr[i+1:])
+			if err1 != nil || err2 != nil || from > to {
+				return 0, fmt.Errorf("invalid range in %s \"%s\"", f.name, part)
+			}
+		default:
+			n, err := strconv.Atoi(rangeExpr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s \"%s\"", f.name, part)
+			}
+			from, to = n, n
+			if step != 1 {
+				to = f.max
+			}
+		}
+		if from < f.min || to > f.max {
+			return 0, fmt.Errorf("%s \"%s\" out of range %d-%d", f.name, part, f.min, f.max)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// has returns whether the value provided is in the bit set provided.
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
@@ -0,0 +1,46 @@
+package schedule_test
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/schedule"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2020, time.January, 31, 10, 30, 15, 0, time.UTC) // Friday
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.January, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2020, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2020, time.February, 3, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2020, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2020, time.February, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 3 */2 * 1", time.Date(2020, time.February, 3, 3, 0, 0, 0, time.UTC)},
+		{"10,20 8-9 1 */3 *", time.Date(2020, time.April, 1, 8, 10, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		s, err := schedule.Parse(test.spec)
+		if err != nil {
+			t.Errorf("error parsing %s: %s", test.spec, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(test.expected) {
+			t.Errorf("unexpected next time of %s: %s, expected %s", test.spec, next, test.expected)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often"} {
+		if _, err := schedule.Parse(spec); err == nil {
+			t.Errorf("not error parsing invalid schedule \"%s\"", spec)
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package pkg
 
 import (
+	"github.com/Miguel-Dorta/gkup/pkg/progress"
 	"github.com/Miguel-Dorta/logolang"
 	"runtime"
 )
 
+// Default settings of the operations. They're only read by NewOptions, so changing them
+// doesn't affect the Options already created.
 var (
 	BufferSize      = 4 * 1024 * 1024
+	CopyWorkers     = 0
+	Exclude         []string
 	Log             = logolang.NewLogger()
 	NumberOfThreads = runtime.NumCPU()
 	OmitHidden      = false
 	OmitErrors      = false
+	Paranoid        = false
+	Progress        progress.Renderer
 	Version         string
 )
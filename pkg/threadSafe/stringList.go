@@ -39,3 +39,10 @@ func (l *StringList) GetPosUnsafe() int {
 func (l *StringList) GetLenUnsafe() int {
 	return len(l.list)
 }
+
+// GetList gets the internal slice of strings
+func (l *StringList) GetList() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.list
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader is a reader that stops reading when its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewContextReader returns a reader that reads from the reader provided until the context provided is cancelled.
+// Once it's cancelled, every read will return the error of the context.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
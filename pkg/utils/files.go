@@ -1,51 +1,86 @@
 package utils
 
 import (
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
-	"github.com/Miguel-Dorta/gkup/pkg"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // CopyFile copies a file from origin path to destiny path
 func CopyFile(origin, destiny string, buffer []byte) error {
+	return CopyFileContext(context.Background(), origin, destiny, buffer)
+}
+
+// CopyFileContext copies a file from origin path to destiny path until the context provided is cancelled.
+// If the copy fails or is interrupted, the partial file in destiny is removed.
+func CopyFileContext(ctx context.Context, origin, destiny string, buffer []byte) error {
 	originFile, err := os.Open(origin)
 	if err != nil {
-		return fmt.Errorf("cannot open file \"%s\": %s", origin, err.Error())
+		return fmt.Errorf("cannot open file \"%s\": %w", origin, err)
 	}
 	defer originFile.Close()
 
 	destinyFile, err := os.Create(destiny)
 	if err != nil {
-		return fmt.Errorf("cannot create file in \"%s\": %s", destiny, err.Error())
+		return fmt.Errorf("cannot create file in \"%s\": %w", destiny, err)
 	}
 	defer destinyFile.Close()
 
-	pkg.Log.Debugf("Copying file %s to %s", origin, destiny)
-	if _, err = io.CopyBuffer(destinyFile, originFile, buffer); err != nil {
-		errStr := fmt.Sprintf("Error copying file from %s to %s: %s", origin, destiny, err.Error())
-		pkg.Log.Error(errStr)
-
+	if _, err = io.CopyBuffer(destinyFile, NewContextReader(ctx, originFile), buffer); err != nil {
+		copyErr := fmt.Errorf("error copying file from %s to %s: %w", origin, destiny, err)
 		if err = destinyFile.Close(); err == nil {
-			pkg.Log.Debugf("File %s closed", destiny)
 			if err = os.Remove(destiny); err == nil {
-				pkg.Log.Debugf("File %s removed", destiny)
-				return errors.New(errStr)
+				return copyErr
 			}
 		}
-
-		errStr = fmt.Sprintf("%s\n-> There's a corrupt file in \"%s\". Please, remove it", errStr, destiny)
-		pkg.Log.Error(errStr)
-		return errors.New(errStr)
+		return fmt.Errorf("%w\n-> There's a corrupt file in \"%s\". Please, remove it", copyErr, destiny)
 	}
 
 	if err = destinyFile.Close(); err != nil {
-		return fmt.Errorf("error closing file in \"%s\", please check it", err)
+		return fmt.Errorf("error closing file in \"%s\", please check it: %w", destiny, err)
 	}
 	return nil
 }
 
+// EqualFiles returns whether the content of the files of the paths provided is the same.
+// Both buffers provided must have the same length.
+func EqualFiles(path1, path2 string, buffer1, buffer2 []byte) (bool, error) {
+	f1, err := os.Open(path1)
+	if err != nil {
+		return false, fmt.Errorf("cannot open file \"%s\": %s", path1, err.Error())
+	}
+	defer f1.Close()
+
+	f2, err := os.Open(path2)
+	if err != nil {
+		return false, fmt.Errorf("cannot open file \"%s\": %s", path2, err.Error())
+	}
+	defer f2.Close()
+
+	for {
+		n1, err1 := io.ReadFull(f1, buffer1)
+		n2, err2 := io.ReadFull(f2, buffer2)
+		if !bytes.Equal(buffer1[:n1], buffer2[:n2]) {
+			return false, nil
+		}
+
+		end1 := err1 == io.EOF || err1 == io.ErrUnexpectedEOF
+		end2 := err2 == io.EOF || err2 == io.ErrUnexpectedEOF
+		if err1 != nil && !end1 {
+			return false, fmt.Errorf("error reading file \"%s\": %s", path1, err1.Error())
+		}
+		if err2 != nil && !end2 {
+			return false, fmt.Errorf("error reading file \"%s\": %s", path2, err2.Error())
+		}
+		if end1 || end2 {
+			return end1 == end2, nil
+		}
+	}
+}
+
 // ListDir lists the directory from the path provided
 func ListDir(path string) ([]os.FileInfo, error) {
 	f, err := os.Open(path)
@@ -57,6 +92,17 @@ func ListDir(path string) ([]os.FileInfo, error) {
 	return f.Readdir(-1)
 }
 
+// IsExcluded returns whether the file name provided matches any of the patterns provided.
+// The patterns follow the syntax of filepath.Match. Malformed patterns never match.
+func IsExcluded(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // IsHidden returns whether the file provided is hidden
 func IsHidden(name string) bool {
 	return isHidden(name)
@@ -0,0 +1,69 @@
+package gkup
+
+import (
+	"github.com/Miguel-Dorta/gkup/pkg/files"
+	repoFiles "github.com/Miguel-Dorta/gkup/pkg/repository/files"
+	"github.com/Miguel-Dorta/gkup/pkg/repository/snapshots"
+	"time"
+)
+
+// Snapshot represents a snapshot of a repository.
+type Snapshot struct {
+	Name string    // Name of the snapshot, empty if it has no name
+	Time time.Time // Time when it was created, in UTC
+
+	repoPath string
+	id       snapshots.ID
+}
+
+// File represents a file of a snapshot.
+type File struct {
+	Size       int64
+	Hash       []byte
+	ObjectPath string // Path of the object of the repository that holds its content
+}
+
+// WalkFunc is the type of the function called for each file visited by Snapshot.Walk.
+// The path provided is relative to the root of the snapshot and uses '/' as separator.
+// If it returns an error, the walk stops and that error is returned.
+type WalkFunc func(path string, f File) error
+
+// newSnapshot returns the Snapshot of the ID provided of the repository of the path provided.
+func newSnapshot(repoPath string, id snapshots.ID) *Snapshot {
+	return &Snapshot{
+		Name:     id.Name,
+		Time:     id.Time,
+		repoPath: repoPath,
+		id:       id,
+	}
+}
+
+// ID returns the ID of the snapshot, in the format "[name/]YYYY-MM-DD_hh-mm-ss".
+func (s *Snapshot) ID() string {
+	return s.id.String()
+}
+
+// Incomplete returns whether the operation that created the snapshot was interrupted.
+func (s *Snapshot) Incomplete() (bool, error) {
+	snap, err := snapshots.Read(s.id.Path(s.repoPath))
+	if err != nil {
+		return false, err
+	}
+	return snap.Incomplete, nil
+}
+
+// Walk calls the function provided for each file of the snapshot, in the order they are saved.
+func (s *Snapshot) Walk(fn WalkFunc) error {
+	snap, err := snapshots.Read(s.id.Path(s.repoPath))
+	if err != nil {
+		return err
+	}
+
+	return snap.Walk(func(path string, f *files.File) error {
+		return fn(path, File{
+			Size:       f.Size,
+			Hash:       f.Hash,
+			ObjectPath: repoFiles.GetObjectPath(s.repoPath, f),
+		})
+	})
+}